@@ -1,13 +1,52 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/k0st1a/gophermart/internal/application"
 	"github.com/rs/zerolog/log"
 )
 
+const usage = `Usage: gophermart [command] [flags]
+
+Commands:
+  serve                      HTTP и gRPC API без опроса системы расчёта начислений и доставки вебхуков
+  worker                     опрос системы расчёта начислений и доставка вебхуков
+  migrate [flags] up         применить все миграции
+  migrate [flags] down [N]   откатить N последних миграций, по умолчанию одну
+  migrate [flags] version    вывести версию последней применённой миграции
+  migrate [flags] force V    записать версию миграции V без её применения и снять признак ошибки
+
+Без команды API и фоновые задачи запускаются в одном процессе. Флаги команды: gophermart [command] -h.`
+
 func main() {
-	err := application.Run()
+	err := run(os.Args[1:])
 	if err != nil {
 		log.Fatal().Err(err).Msg("")
 	}
 }
+
+func run(args []string) error {
+	command := ""
+	if len(args) != 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "":
+		return application.Run(application.ModeAll, args)
+	case "serve":
+		return application.Run(application.ModeServe, args)
+	case "worker":
+		return application.Run(application.ModeWorker, args)
+	case "migrate":
+		return application.Migrate(args)
+	case "help":
+		fmt.Println(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command:%q, see gophermart help", command)
+	}
+}
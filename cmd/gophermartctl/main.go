@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/k0st1a/gophermart/internal/adapters/cli"
+	"github.com/k0st1a/gophermart/internal/application"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	args := os.Args[1:]
+	if len(args) == 1 && args[0] == "help" {
+		fmt.Println(cli.Usage)
+		return
+	}
+
+	err := application.Ctl(args)
+	if errors.Is(err, cli.ErrUsage) {
+		fmt.Fprintln(os.Stderr, cli.Usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msg("")
+	}
+}
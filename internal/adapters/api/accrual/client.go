@@ -10,8 +10,10 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/k0st1a/gophermart/internal/pkg/metrics"
 	"github.com/k0st1a/gophermart/internal/ports"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type Accrual struct {
@@ -26,16 +28,20 @@ type client struct {
 	address string
 }
 
-func NewClient(address string, block Blocker) *client {
+// NewClient создаёт клиент системы расчёта начислений с адресом address. Запрос ограничен временем timeout.
+func NewClient(address string, timeout time.Duration, block Blocker) *client {
 	return &client{
 		address: address,
-		client:  &http.Client{},
-		block:   block,
+		client: &http.Client{
+			Transport: otelhttp.NewTransport(http.DefaultTransport),
+			Timeout:   timeout,
+		},
+		block: block,
 	}
 }
 
 func (c *client) Get(ctx context.Context, order string) (*ports.Accrual, error) {
-	log.Printf("Get accrual for order with number:%s", order)
+	log.Ctx(ctx).Debug().Str("order", order).Msg("get accrual")
 
 	if c.block.IsActive() {
 		return nil, ports.ErrBlocked
@@ -46,8 +52,6 @@ func (c *client) Get(ctx context.Context, order string) (*ports.Accrual, error)
 		return nil, fmt.Errorf("url error of join path:%w", err)
 	}
 
-	log.Printf("For order:%s, url:%s", order, url)
-
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("http error of new request with context:%w", err)
@@ -55,24 +59,23 @@ func (c *client) Get(ctx context.Context, order string) (*ports.Accrual, error)
 
 	resp, err := c.client.Do(req)
 	if err != nil {
+		metrics.AccrualRequests.WithLabelValues(metrics.AccrualOutcomeError).Inc()
 		return nil, fmt.Errorf("error of client do:%w", err)
 	}
+	metrics.AccrualRequests.WithLabelValues(metrics.AccrualOutcome(resp.StatusCode)).Inc()
 	defer func() {
 		_ = resp.Body.Close()
 	}()
 
 	if resp.StatusCode == http.StatusNoContent {
-		log.Printf("For order:%s, StatusNoContent", order)
+		log.Ctx(ctx).Debug().Str("order", order).Msg("order not registered in accrual")
 		return nil, ports.ErrOrderNotRegistered
 	}
 
 	if resp.StatusCode == http.StatusTooManyRequests {
-		log.Printf("For order:%s, too many requests", order)
-
 		retryHeader := resp.Header.Get("Retry-After")
-		log.Printf("For order:%s, retryHeader:%v", order, retryHeader)
+		log.Ctx(ctx).Warn().Str("order", order).Str("retry_after", retryHeader).Msg("too many requests to accrual")
 		retryAfter, err := strconv.Atoi(retryHeader)
-		log.Printf("For order:%s, retryAfter:%v", order, retryAfter)
 		if err != nil {
 			return nil, ports.ErrTooManyRequests
 		}
@@ -83,20 +86,21 @@ func (c *client) Get(ctx context.Context, order string) (*ports.Accrual, error)
 	}
 
 	if resp.StatusCode == http.StatusOK {
-		log.Printf("For order:%s, StatusOK", order)
-
 		data, err := io.ReadAll(resp.Body)
 		if err != nil {
 			return nil, fmt.Errorf("io error of read body:%w", err)
 		}
-		log.Printf("For order:%s, data:%s", order, string(data))
 
 		var accrual Accrual
 		err = json.Unmarshal(data, &accrual)
 		if err != nil {
 			return nil, fmt.Errorf("unmarshal error:%w", err)
 		}
-		log.Printf("For order:%s, accrual:%v", order, accrual)
+		log.Ctx(ctx).Debug().
+			Str("order", order).
+			Str("status", accrual.Status).
+			Float64("accrual", accrual.Accrual).
+			Msg("got accrual")
 
 		return &ports.Accrual{
 			Order:   accrual.Order,
@@ -22,10 +22,10 @@ func NewBlock() Blocker {
 
 func (b *block) Activate(wait time.Duration) {
 	b.active.Store(true)
-	log.Printf("Active block for %s seconds", wait)
+	log.Info().Dur("wait", wait).Msg("accrual requests blocked")
 	time.Sleep(wait)
 	b.active.Store(false)
-	log.Printf("Deactivate block after %s seconds", wait)
+	log.Info().Dur("wait", wait).Msg("accrual requests unblocked")
 }
 
 func (b *block) IsActive() bool {
@@ -0,0 +1,95 @@
+package rest
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultMaxBodySize максимальный размер тела запроса по умолчанию, 1 МБ.
+const DefaultMaxBodySize = 1 << 20
+
+// compressLevel уровень сжатия ответов.
+const compressLevel = 5
+
+// compressibleTypes типы ответов, которые сжимаются. text/event-stream не сжимается: иначе события
+// задерживались бы в буфере компрессора.
+var compressibleTypes = []string{
+	"application/json",
+	"application/problem+json",
+	"text/plain",
+}
+
+// limitBody распаковывает тело запроса со сжатием gzip или deflate (заголовок Content-Encoding) и ограничивает
+// размер тела после распаковки величиной maxBytes. Превышение обнаруживается при чтении тела, см. readBody.
+func limitBody(maxBytes int64) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			switch strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))) {
+			case "", "identity":
+			case "gzip", "x-gzip":
+				zr, err := gzip.NewReader(r.Body)
+				if err != nil {
+					log.Ctx(r.Context()).Error().Err(err).Msg("error of create gzip reader")
+					writeProblemDetail(rw, r, http.StatusBadRequest, codeInvalidRequest, "invalid gzip body")
+					return
+				}
+				defer func() {
+					_ = zr.Close()
+				}()
+				r.Body = zr
+				r.Header.Del("Content-Encoding")
+				r.ContentLength = -1
+			case "deflate":
+				// Кодирование deflate в HTTP - поток zlib (RFC 1950), а не «сырой» DEFLATE.
+				zr, err := zlib.NewReader(r.Body)
+				if err != nil {
+					log.Ctx(r.Context()).Error().Err(err).Msg("error of create zlib reader")
+					writeProblemDetail(rw, r, http.StatusBadRequest, codeInvalidRequest, "invalid deflate body")
+					return
+				}
+				defer func() {
+					_ = zr.Close()
+				}()
+				r.Body = zr
+				r.Header.Del("Content-Encoding")
+				r.ContentLength = -1
+			default:
+				writeProblem(rw, r, http.StatusUnsupportedMediaType, codeUnsupportedEncoding)
+				return
+			}
+
+			if r.ContentLength > maxBytes {
+				writeProblem(rw, r, http.StatusRequestEntityTooLarge, codeBodyTooLarge)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(rw, r.Body, maxBytes)
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// readBody читает тело запроса. При ошибке пишет ответ сам (413, если тело больше допустимого, иначе 400)
+// и возвращает false.
+func readBody(rw http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		var mbe *http.MaxBytesError
+		if errors.As(err, &mbe) {
+			writeProblem(rw, r, http.StatusRequestEntityTooLarge, codeBodyTooLarge)
+			return nil, false
+		}
+
+		log.Ctx(r.Context()).Error().Err(err).Msg("body read error")
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidRequest)
+		return nil, false
+	}
+
+	return data, true
+}
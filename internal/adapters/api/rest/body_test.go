@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipString(t *testing.T, s string) []byte {
+	t.Helper()
+
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	_, err := zw.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	return b.Bytes()
+}
+
+func zlibString(t *testing.T, s string) []byte {
+	t.Helper()
+
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	_, err := zw.Write([]byte(s))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	return b.Bytes()
+}
+
+func TestLimitBody(t *testing.T) {
+	echo := limitBody(16)(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		data, ok := readBody(rw, r)
+		if !ok {
+			return
+		}
+		_, _ = rw.Write(data)
+	}))
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		status   int
+		want     string
+	}{
+		{name: "plain", body: []byte("12345678903"), status: http.StatusOK, want: "12345678903"},
+		{name: "gzip", encoding: "gzip", body: gzipString(t, "12345678903"), status: http.StatusOK, want: "12345678903"},
+		{name: "too large", body: []byte(strings.Repeat("1", 17)), status: http.StatusRequestEntityTooLarge},
+		{
+			name:     "too large after decompression",
+			encoding: "gzip",
+			body:     gzipString(t, strings.Repeat("1", 1000)),
+			status:   http.StatusRequestEntityTooLarge,
+		},
+		{name: "invalid gzip", encoding: "gzip", body: []byte("12345678903"), status: http.StatusBadRequest},
+		{
+			name:     "deflate",
+			encoding: "deflate",
+			body:     zlibString(t, "12345678903"),
+			status:   http.StatusOK,
+			want:     "12345678903",
+		},
+		{name: "invalid deflate", encoding: "deflate", body: []byte("12345678903"), status: http.StatusBadRequest},
+		{name: "unsupported encoding", encoding: "br", body: []byte("1"), status: http.StatusUnsupportedMediaType},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/api/user/orders", bytes.NewReader(test.body))
+			if test.encoding != "" {
+				r.Header.Set("Content-Encoding", test.encoding)
+			}
+			rw := httptest.NewRecorder()
+
+			echo.ServeHTTP(rw, r)
+
+			assert.Equal(t, test.status, rw.Code)
+			if test.status == http.StatusOK {
+				assert.Equal(t, test.want, rw.Body.String())
+			}
+		})
+	}
+}
+
+func TestCompressResponse(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/openapi.json", http.NoBody)
+	r.Header.Set("Accept-Encoding", "gzip")
+	rw := httptest.NewRecorder()
+
+	newTestRouter(t, false).ServeHTTP(rw, r)
+
+	require.Equal(t, http.StatusOK, rw.Code)
+	require.Equal(t, "gzip", rw.Header().Get("Content-Encoding"))
+
+	zr, err := gzip.NewReader(rw.Body)
+	require.NoError(t, err)
+	data, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, openAPISpec, data)
+}
@@ -3,14 +3,23 @@ package rest
 import (
 	"encoding/json"
 	"errors"
-	"io"
+	"net"
 	"net/http"
 	"strconv"
 
 	"github.com/ShiraazMoollatjie/goluhn"
+	"github.com/go-chi/chi/v5"
+	"github.com/k0st1a/gophermart/internal/pkg/admin"
+	"github.com/k0st1a/gophermart/internal/pkg/apikey"
 	"github.com/k0st1a/gophermart/internal/pkg/auth"
+	"github.com/k0st1a/gophermart/internal/pkg/events"
+	"github.com/k0st1a/gophermart/internal/pkg/export"
 	"github.com/k0st1a/gophermart/internal/pkg/order"
+	"github.com/k0st1a/gophermart/internal/pkg/session"
+	"github.com/k0st1a/gophermart/internal/pkg/sso"
+	"github.com/k0st1a/gophermart/internal/pkg/tracing"
 	"github.com/k0st1a/gophermart/internal/pkg/user"
+	"github.com/k0st1a/gophermart/internal/pkg/webhook"
 	"github.com/k0st1a/gophermart/internal/pkg/withdraw"
 	"github.com/rs/zerolog/log"
 )
@@ -20,56 +29,97 @@ type handler struct {
 	user     user.Managment
 	order    order.Managment
 	withdraw withdraw.Managment
+	admin    admin.Managment
+	apikey   apikey.Managment
+	sso      sso.Provider
+	export   export.Managment
+	session  session.Managment
+	issuer   session.Issuer
+	events   events.Managment
+	webhook  webhook.Managment
 }
 
-func NewHandler(a auth.UserAuthentication, u user.Managment, o order.Managment, w withdraw.Managment) *handler {
+// NewHandler создаёт обработчики REST API. Провайдер sso опционален: если он nil,
+// вход через OpenID Connect отключён.
+func NewHandler(a auth.UserAuthentication, u user.Managment, o order.Managment, w withdraw.Managment,
+	adm admin.Managment, k apikey.Managment, p sso.Provider, e export.Managment, s session.Managment,
+	ev events.Managment, wh webhook.Managment) *handler {
 	return &handler{
 		auth:     a,
 		user:     u,
 		order:    o,
 		withdraw: w,
+		admin:    adm,
+		apikey:   k,
+		sso:      p,
+		export:   e,
+		session:  s,
+		issuer:   session.NewIssuer(a, u, s),
+		events:   ev,
+		webhook:  wh,
 	}
 }
 
 func (h *handler) register(rw http.ResponseWriter, r *http.Request) {
-	data, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Error().Err(err).Msg("io.ReadAll error")
-		rw.WriteHeader(http.StatusInternalServerError)
+	t, ok := h.registerUser(rw, r)
+	if !ok {
 		return
 	}
 
+	rw.Header().Set("Authorization", t)
+	rw.WriteHeader(http.StatusOK)
+}
+
+// registerUser регистрирует пользователя по телу запроса Register и возвращает токен.
+// При ошибке пишет ответ сам и возвращает false.
+func (h *handler) registerUser(rw http.ResponseWriter, r *http.Request) (string, bool) {
+	data, ok := readBody(rw, r)
+	if !ok {
+		return "", false
+	}
+
 	var ur Register
-	err = json.Unmarshal(data, &ur)
+	err := json.Unmarshal(data, &ur)
 	if err != nil {
-		log.Error().Err(err).Msg("user registration deserialize error")
-		rw.WriteHeader(http.StatusBadRequest)
-		return
+		log.Ctx(r.Context()).Error().Err(err).Msg("user registration deserialize error")
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidRequest)
+		return "", false
 	}
 
+	_, span := tracing.Tracer().Start(r.Context(), "bcrypt.generate")
 	passwordHash, err := h.auth.GeneratePasswordHash(ur.Password)
+	span.End()
 	if err != nil {
-		log.Error().Err(err).Msg("error of generate password hash")
-		rw.WriteHeader(http.StatusInternalServerError)
-		return
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of generate password hash")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return "", false
 	}
 
 	id, err := h.user.Create(r.Context(), ur.Login, passwordHash)
 	if err != nil {
 		if errors.Is(err, user.ErrLoginAlreadyBusy) {
-			rw.WriteHeader(http.StatusConflict)
-			return
+			writeProblem(rw, r, http.StatusConflict, codeLoginTaken)
+			return "", false
 		}
 
-		log.Error().Err(err).Msg("error of create user")
-		rw.WriteHeader(http.StatusInternalServerError)
-		return
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of create user")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return "", false
 	}
 
-	t, err := h.auth.GenerateToken(id)
+	t, err := h.issuer.Issue(r.Context(), id, clientOf(r))
 	if err != nil {
-		log.Error().Err(err).Msg("error of generate token")
-		rw.WriteHeader(http.StatusInternalServerError)
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of issue token")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return "", false
+	}
+
+	return t, true
+}
+
+func (h *handler) login(rw http.ResponseWriter, r *http.Request) {
+	t, ok := h.loginUser(rw, r)
+	if !ok {
 		return
 	}
 
@@ -77,173 +127,249 @@ func (h *handler) register(rw http.ResponseWriter, r *http.Request) {
 	rw.WriteHeader(http.StatusOK)
 }
 
-func (h *handler) login(rw http.ResponseWriter, r *http.Request) {
-	data, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Error().Err(err).Msg("io.ReadAll error")
-		rw.WriteHeader(http.StatusInternalServerError)
-		return
+// loginUser аутентифицирует пользователя по телу запроса Login и возвращает токен.
+// При ошибке пишет ответ сам и возвращает false.
+func (h *handler) loginUser(rw http.ResponseWriter, r *http.Request) (string, bool) {
+	data, ok := readBody(rw, r)
+	if !ok {
+		return "", false
 	}
 
 	var ul Login
-	err = json.Unmarshal(data, &ul)
+	err := json.Unmarshal(data, &ul)
 	if err != nil {
-		log.Error().Err(err).Msg("user login deserialize error")
-		http.Error(rw, "deserialize error", http.StatusBadRequest)
-		return
+		log.Ctx(r.Context()).Error().Err(err).Msg("user login deserialize error")
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidRequest)
+		return "", false
 	}
 
-	userID, password, err := h.user.GetIDAndPassword(r.Context(), ul.Login)
+	t, err := h.issuer.Login(r.Context(), ul.Login, ul.Password, clientOf(r))
 	if err != nil {
-		if errors.Is(err, user.ErrNotFound) {
-			rw.WriteHeader(http.StatusConflict)
-			return
+		switch {
+		case errors.Is(err, session.ErrInvalidCredentials):
+			writeProblem(rw, r, http.StatusConflict, codeInvalidCredentials)
+		case errors.Is(err, user.ErrLocked):
+			writeProblem(rw, r, http.StatusForbidden, codeAccountLocked)
+		default:
+			log.Ctx(r.Context()).Error().Err(err).Msg("error of login")
+			writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
 		}
-
-		log.Error().Err(err).Msg("error of get user id and password")
-		rw.WriteHeader(http.StatusInternalServerError)
-		return
+		return "", false
 	}
 
-	err = h.auth.CheckPasswordHash(ul.Password, password)
-	if err != nil {
-		rw.WriteHeader(http.StatusConflict)
-		return
-	}
+	return t, true
+}
 
-	t, err := h.auth.GenerateToken(userID)
+// clientOf возвращает клиента запроса r для новой сессии.
+func clientOf(r *http.Request) session.Client {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		log.Error().Err(err).Msg("error of generate token")
-		rw.WriteHeader(http.StatusInternalServerError)
-		return
+		ip = r.RemoteAddr
 	}
 
-	rw.Header().Set("Authorization", t)
-	rw.WriteHeader(http.StatusOK)
+	return session.Client{UserAgent: r.UserAgent(), IP: ip}
 }
 
 func (h *handler) createOrder(rw http.ResponseWriter, r *http.Request) {
 	userID, err := getUserID(r.Context())
 	if err != nil {
-		rw.WriteHeader(http.StatusUnauthorized)
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
 		return
 	}
 
-	data, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Error().Err(err).Msg("body read error")
-		http.Error(rw, err.Error(), http.StatusBadRequest)
+	data, ok := readBody(rw, r)
+	if !ok {
 		return
 	}
 
-	orderNumber := string(data)
-	err = goluhn.Validate(orderNumber)
-	if err != nil {
-		http.Error(rw, "invalid order number format", http.StatusUnprocessableEntity)
+	_, status, ok := h.uploadOrder(rw, r, userID, string(data))
+	if !ok {
 		return
 	}
 
-	orderID, err := strconv.ParseInt(orderNumber, 10, 64)
+	rw.WriteHeader(status)
+}
+
+// uploadOrder загружает номер заказа пользователя и возвращает номер заказа и код успешного ответа:
+// http.StatusAccepted для нового заказа, http.StatusOK для уже загруженного этим пользователем.
+// При ошибке пишет ответ сам и возвращает false.
+func (h *handler) uploadOrder(rw http.ResponseWriter, r *http.Request, userID int64, number string) (int64, int,
+	bool) {
+	err := goluhn.Validate(number)
 	if err != nil {
-		log.Error().Err(err).Msg("order number parsing error")
-		http.Error(rw, err.Error(), http.StatusBadRequest)
-		return
+		writeProblem(rw, r, http.StatusUnprocessableEntity, codeInvalidOrderNumber)
+		return 0, 0, false
+	}
+
+	orderID, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("order number parsing error")
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidOrderNumber)
+		return 0, 0, false
 	}
 
 	err = h.order.Create(r.Context(), userID, orderID)
 	if err != nil {
 		switch {
 		case errors.Is(err, order.ErrAlreadyUploadedByThisUser):
-			rw.WriteHeader(http.StatusOK)
-			return
+			return orderID, http.StatusOK, true
 		case errors.Is(err, order.ErrAlreadyUploadedByAnotherUser):
-			rw.WriteHeader(http.StatusConflict)
-			return
+			writeProblem(rw, r, http.StatusConflict, codeOrderConflict)
+			return 0, 0, false
 		default:
-			log.Error().Err(err).Msg("create order error")
-			rw.WriteHeader(http.StatusInternalServerError)
-			return
+			log.Ctx(r.Context()).Error().Err(err).Msg("create order error")
+			writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+			return 0, 0, false
 		}
 	}
 
-	rw.WriteHeader(http.StatusAccepted)
+	return orderID, http.StatusAccepted, true
 }
 
-//nolint:dupl //similar to getWithdrawals
 func (h *handler) getOrders(rw http.ResponseWriter, r *http.Request) {
 	userID, err := getUserID(r.Context())
 	if err != nil {
-		rw.WriteHeader(http.StatusUnauthorized)
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
 		return
 	}
 
-	orders, err := h.order.List(r.Context(), userID)
+	h.writeOrders(rw, r, userID)
+}
+
+//nolint:dupl //similar to writeWithdrawals
+func (h *handler) writeOrders(rw http.ResponseWriter, r *http.Request, userID int64) {
+	// Без limit и after список отдаётся целиком, как до появления постраничной выдачи в API v1.
+	filter, err := parseListFilter(r, true, 0)
+	if err != nil {
+		writeProblemDetail(rw, r, http.StatusBadRequest, codeInvalidListParam, err.Error())
+		return
+	}
+
+	orders, err := h.order.List(r.Context(), userID, filter)
 	if err != nil {
-		log.Error().Err(err).Msg("error of get orders")
-		rw.WriteHeader(http.StatusInternalServerError)
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get orders")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
 		return
 	}
 
-	log.Printf("orders:%+v", orders)
+	log.Ctx(r.Context()).Debug().Int("count", len(orders)).Msg("got orders")
 
 	if len(orders) == 0 {
 		rw.WriteHeader(http.StatusNoContent)
 		return
 	}
 
+	setNextLink(rw, r, filter, len(orders), orders[len(orders)-1].Number)
+
 	modelOrders := make([]Order, len(orders))
 	for i := 0; i < len(orders); i++ {
 		modelOrders[i] = Order(orders[i])
 	}
-	log.Printf("modelOrders:%+v", modelOrders)
 
 	data, err := json.Marshal(&modelOrders)
 	if err != nil {
-		log.Error().Err(err).Msg("error of serialize orders")
-		rw.WriteHeader(http.StatusInternalServerError)
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize orders")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
 		return
 	}
 
 	rw.Header().Set("Content-Type", "application/json")
 	_, err = rw.Write(data)
 	if err != nil {
-		log.Error().Err(err).Msg("error of write orders")
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write orders")
 		return
 	}
+}
 
-	rw.WriteHeader(http.StatusOK)
+func (h *handler) getOrder(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	d, ok := h.lookupOrder(rw, r, userID)
+	if !ok {
+		return
+	}
+
+	od := OrderDetails{
+		Order: Order(d.Order),
+		Polls: make([]OrderPoll, len(d.Polls)),
+	}
+	for i := range d.Polls {
+		od.Polls[i] = OrderPoll(d.Polls[i])
+	}
+
+	data, err := json.Marshal(&od)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize order")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	_, err = rw.Write(data)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write order")
+		return
+	}
+}
+
+// lookupOrder возвращает заказ пользователя по номеру из пути запроса. При ошибке пишет ответ сам
+// и возвращает false.
+func (h *handler) lookupOrder(rw http.ResponseWriter, r *http.Request, userID int64) (*order.Details, bool) {
+	orderID, err := strconv.ParseInt(chi.URLParam(r, "number"), 10, 64)
+	if err != nil {
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidOrderNumber)
+		return nil, false
+	}
+
+	d, err := h.order.Get(r.Context(), userID, orderID)
+	if err != nil {
+		if errors.Is(err, order.ErrNotFound) {
+			writeProblem(rw, r, http.StatusNotFound, codeNotFound)
+			return nil, false
+		}
+
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get order")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return nil, false
+	}
+
+	return d, true
 }
 
 func (h *handler) getBalance(rw http.ResponseWriter, r *http.Request) {
 	userID, err := getUserID(r.Context())
 	if err != nil {
-		rw.WriteHeader(http.StatusUnauthorized)
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
 		return
 	}
 
 	current, withdrawn, err := h.user.GetBalance(r.Context(), userID)
 	if err != nil {
-		log.Error().Err(err).Msg("error of get balance")
-		rw.WriteHeader(http.StatusInternalServerError)
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get balance")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
 		return
 	}
 
-	log.Printf("current:%v, Withdrawn:%v", current, withdrawn)
+	log.Ctx(r.Context()).Debug().Float64("current", current).Float64("withdrawn", withdrawn).Msg("got balance")
 
 	data, err := json.Marshal(&Balance{
 		Current:   current,
 		Withdrawn: withdrawn,
 	})
 	if err != nil {
-		log.Error().Err(err).Msg("error of serialize balance")
-		rw.WriteHeader(http.StatusInternalServerError)
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize balance")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
 		return
 	}
 
 	rw.Header().Set("Content-Type", "application/json")
 	_, err = rw.Write(data)
 	if err != nil {
-		log.Error().Err(err).Msg("error of write balance")
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write balance")
 		return
 	}
 
@@ -253,96 +379,118 @@ func (h *handler) getBalance(rw http.ResponseWriter, r *http.Request) {
 func (h *handler) createWithdraw(rw http.ResponseWriter, r *http.Request) {
 	userID, err := getUserID(r.Context())
 	if err != nil {
-		rw.WriteHeader(http.StatusUnauthorized)
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
 		return
 	}
 
-	data, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Error().Err(err).Msg("body read error")
-		http.Error(rw, err.Error(), http.StatusBadRequest)
+	data, ok := readBody(rw, r)
+	if !ok {
 		return
 	}
-	log.Printf("createWithdraw, data:%s", string(data))
 
 	var w Withdraw
 	err = json.Unmarshal(data, &w)
 	if err != nil {
-		log.Error().Err(err).Msg("withdraw deserialize error")
-		rw.WriteHeader(http.StatusBadRequest)
+		log.Ctx(r.Context()).Error().Err(err).Msg("withdraw deserialize error")
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidRequest)
 		return
 	}
-	log.Printf("createWithdraw, withdraw:%+v", w)
+	log.Ctx(r.Context()).Debug().Str("order", w.Order).Float64("sum", w.Sum).Msg("createWithdraw")
 
-	err = goluhn.Validate(w.Order)
-	if err != nil {
-		http.Error(rw, "invalid order number format", http.StatusUnprocessableEntity)
+	if !h.withdrawPoints(rw, r, userID, w.Order, w.Sum) {
 		return
 	}
 
-	orderID, err := strconv.ParseInt(w.Order, 10, 64)
+	rw.WriteHeader(http.StatusOK)
+}
+
+// withdrawPoints списывает sum баллов в счёт заказа number. При ошибке пишет ответ сам и возвращает false.
+func (h *handler) withdrawPoints(rw http.ResponseWriter, r *http.Request, userID int64, number string,
+	sum float64) bool {
+	if !withdraw.ValidSum(sum) {
+		writeProblemDetail(rw, r, http.StatusUnprocessableEntity, codeValidationFailed, "sum must be positive")
+		return false
+	}
+
+	err := goluhn.Validate(number)
 	if err != nil {
-		log.Error().Err(err).Msg("order number parsing error")
-		http.Error(rw, err.Error(), http.StatusBadRequest)
-		return
+		writeProblem(rw, r, http.StatusUnprocessableEntity, codeInvalidOrderNumber)
+		return false
+	}
+
+	orderID, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("order number parsing error")
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidOrderNumber)
+		return false
 	}
 
-	err = h.withdraw.Create(r.Context(), userID, orderID, w.Sum)
+	err = h.withdraw.Create(r.Context(), userID, orderID, sum)
 	if err != nil {
 		if errors.Is(err, withdraw.ErrNotEnoughFunds) {
-			rw.WriteHeader(http.StatusPaymentRequired)
-			return
+			writeProblem(rw, r, http.StatusPaymentRequired, codeInsufficientFunds)
+			return false
 		}
 
-		log.Error().Err(err).Msg("error of create withdraw")
-		http.Error(rw, err.Error(), http.StatusInternalServerError)
-		return
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of create withdraw")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return false
 	}
 
-	rw.WriteHeader(http.StatusOK)
+	return true
 }
 
-//nolint:dupl //similar to getOrders
 func (h *handler) getWithdrawals(rw http.ResponseWriter, r *http.Request) {
 	userID, err := getUserID(r.Context())
 	if err != nil {
-		rw.WriteHeader(http.StatusUnauthorized)
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	h.writeWithdrawals(rw, r, userID)
+}
+
+//nolint:dupl //similar to writeOrders
+func (h *handler) writeWithdrawals(rw http.ResponseWriter, r *http.Request, userID int64) {
+	// Без limit и after список отдаётся целиком, как до появления постраничной выдачи в API v1.
+	filter, err := parseListFilter(r, false, 0)
+	if err != nil {
+		writeProblemDetail(rw, r, http.StatusBadRequest, codeInvalidListParam, err.Error())
 		return
 	}
 
-	withdrawals, err := h.withdraw.List(r.Context(), userID)
+	withdrawals, err := h.withdraw.List(r.Context(), userID, filter)
 	if err != nil {
-		log.Error().Err(err).Msg("error of get withdrawals")
-		rw.WriteHeader(http.StatusInternalServerError)
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get withdrawals")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
 		return
 	}
 
-	log.Printf("withdrawals:%+v", withdrawals)
+	log.Ctx(r.Context()).Debug().Int("count", len(withdrawals)).Msg("got withdrawals")
 
 	if len(withdrawals) == 0 {
 		rw.WriteHeader(http.StatusNoContent)
 		return
 	}
 
+	setNextLink(rw, r, filter, len(withdrawals), withdrawals[len(withdrawals)-1].ID)
+
 	modelWithdrawals := make([]WithdrawOut, len(withdrawals))
 	for i := 0; i < len(withdrawals); i++ {
 		modelWithdrawals[i] = WithdrawOut(withdrawals[i])
 	}
-	log.Printf("modelWithdrawals:%+v", modelWithdrawals)
 
 	data, err := json.Marshal(&modelWithdrawals)
 	if err != nil {
-		log.Error().Err(err).Msg("error of serialize withdrawals")
-		rw.WriteHeader(http.StatusInternalServerError)
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize withdrawals")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
 		return
 	}
 
 	rw.Header().Set("Content-Type", "application/json")
 	_, err = rw.Write(data)
 	if err != nil {
-		log.Error().Err(err).Msg("error of write withdrawals")
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write withdrawals")
 		return
 	}
-
-	rw.WriteHeader(http.StatusOK)
 }
@@ -0,0 +1,90 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/k0st1a/gophermart/internal/pkg/export"
+	"github.com/k0st1a/gophermart/internal/pkg/user"
+	"github.com/rs/zerolog/log"
+)
+
+func (h *handler) exportUser(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	a, err := h.export.Export(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, export.ErrUserNotFound) {
+			writeProblem(rw, r, http.StatusNotFound, codeNotFound)
+			return
+		}
+
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of export user")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	e := Export{
+		ExportedAt: a.ExportedAt,
+		Login:      a.Login,
+		Role:       a.Role,
+		Balance: Balance{
+			Current:   a.Balance,
+			Withdrawn: a.Withdrawn,
+		},
+		Orders:         make([]Order, len(a.Orders)),
+		Withdrawals:    make([]WithdrawOut, len(a.Withdrawals)),
+		BalanceHistory: make([]BalanceEntry, len(a.BalanceHistory)),
+	}
+	for i := range a.Orders {
+		e.Orders[i] = Order(a.Orders[i])
+	}
+	for i := range a.Withdrawals {
+		e.Withdrawals[i] = WithdrawOut(a.Withdrawals[i])
+	}
+	for i := range a.BalanceHistory {
+		e.BalanceHistory[i] = BalanceEntry(a.BalanceHistory[i])
+	}
+
+	data, err := json.Marshal(&e)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize export")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Header().Set("Content-Disposition", `attachment; filename="gophermart-export.json"`)
+	_, err = rw.Write(data)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write export")
+		return
+	}
+}
+
+func (h *handler) deleteUser(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	err = h.user.Delete(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			writeProblem(rw, r, http.StatusNotFound, codeNotFound)
+			return
+		}
+
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of delete user")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
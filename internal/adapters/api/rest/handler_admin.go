@@ -0,0 +1,250 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/k0st1a/gophermart/internal/pkg/admin"
+	"github.com/k0st1a/gophermart/internal/pkg/apikey"
+	"github.com/rs/zerolog/log"
+)
+
+func (h *handler) getAdminUser(rw http.ResponseWriter, r *http.Request) {
+	u, ok := h.lookupUser(rw, r)
+	if !ok {
+		return
+	}
+
+	data, err := json.Marshal(&AdminUser{
+		ID:        u.ID,
+		Login:     u.Login,
+		Role:      u.Role,
+		Balance:   u.Balance,
+		Withdrawn: u.Withdrawn,
+	})
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize user")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	_, err = rw.Write(data)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write user")
+		return
+	}
+}
+
+func (h *handler) getAdminUserOrders(rw http.ResponseWriter, r *http.Request) {
+	u, ok := h.lookupUser(rw, r)
+	if !ok {
+		return
+	}
+
+	h.writeOrders(rw, r, u.ID)
+}
+
+func (h *handler) getAdminUserWithdrawals(rw http.ResponseWriter, r *http.Request) {
+	u, ok := h.lookupUser(rw, r)
+	if !ok {
+		return
+	}
+
+	h.writeWithdrawals(rw, r, u.ID)
+}
+
+func (h *handler) adjustBalance(rw http.ResponseWriter, r *http.Request) {
+	adminID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	u, ok := h.lookupUser(rw, r)
+	if !ok {
+		return
+	}
+
+	data, ok := readBody(rw, r)
+	if !ok {
+		return
+	}
+
+	var ba BalanceAdjustment
+	err = json.Unmarshal(data, &ba)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("balance adjustment deserialize error")
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidRequest)
+		return
+	}
+
+	err = h.admin.AdjustBalance(r.Context(), adminID, u.ID, ba.Amount, ba.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, admin.ErrEmptyReason), errors.Is(err, admin.ErrZeroAmount),
+			errors.Is(err, admin.ErrNotFiniteAmount):
+			writeProblemDetail(rw, r, http.StatusBadRequest, codeValidationFailed, err.Error())
+			return
+		case errors.Is(err, admin.ErrNegativeBalance):
+			writeProblemDetail(rw, r, http.StatusConflict, codeNegativeBalance, err.Error())
+			return
+		default:
+			log.Ctx(r.Context()).Error().Err(err).Msg("error of adjust balance")
+			writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+			return
+		}
+	}
+
+	rw.WriteHeader(http.StatusOK)
+}
+
+func (h *handler) createAPIKey(rw http.ResponseWriter, r *http.Request) {
+	u, ok := h.lookupUser(rw, r)
+	if !ok {
+		return
+	}
+
+	data, ok := readBody(rw, r)
+	if !ok {
+		return
+	}
+
+	var ak APIKeyIn
+	err := json.Unmarshal(data, &ak)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("api key deserialize error")
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidRequest)
+		return
+	}
+
+	k, key, err := h.apikey.Create(r.Context(), u.ID, ak.Name, ak.Scopes)
+	if err != nil {
+		switch {
+		case errors.Is(err, apikey.ErrEmptyName),
+			errors.Is(err, apikey.ErrEmptyScopes),
+			errors.Is(err, apikey.ErrUnknownScope):
+			writeProblemDetail(rw, r, http.StatusBadRequest, codeValidationFailed, err.Error())
+			return
+		default:
+			log.Ctx(r.Context()).Error().Err(err).Msg("error of create api key")
+			writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+			return
+		}
+	}
+
+	out := toAPIKeyOut(k)
+	out.Key = key
+
+	data, err = json.Marshal(&out)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize api key")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusCreated)
+	_, err = rw.Write(data)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write api key")
+		return
+	}
+}
+
+func (h *handler) getAPIKeys(rw http.ResponseWriter, r *http.Request) {
+	u, ok := h.lookupUser(rw, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.apikey.List(r.Context(), u.ID)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get api keys")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	if len(keys) == 0 {
+		rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	modelKeys := make([]APIKeyOut, len(keys))
+	for i := range keys {
+		modelKeys[i] = toAPIKeyOut(&keys[i])
+	}
+
+	data, err := json.Marshal(&modelKeys)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize api keys")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	_, err = rw.Write(data)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write api keys")
+		return
+	}
+}
+
+func (h *handler) revokeAPIKey(rw http.ResponseWriter, r *http.Request) {
+	u, ok := h.lookupUser(rw, r)
+	if !ok {
+		return
+	}
+
+	keyID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidRequest)
+		return
+	}
+
+	err = h.apikey.Revoke(r.Context(), u.ID, keyID)
+	if err != nil {
+		if errors.Is(err, apikey.ErrNotFound) {
+			writeProblem(rw, r, http.StatusNotFound, codeNotFound)
+			return
+		}
+
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of revoke api key")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func toAPIKeyOut(k *apikey.Key) APIKeyOut {
+	return APIKeyOut{
+		ID:         k.ID,
+		Name:       k.Name,
+		Scopes:     k.Scopes,
+		CreatedAt:  k.CreatedAt,
+		LastUsedAt: k.LastUsedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
+
+func (h *handler) lookupUser(rw http.ResponseWriter, r *http.Request) (*admin.User, bool) {
+	login := chi.URLParam(r, "login")
+
+	u, err := h.admin.GetUser(r.Context(), login)
+	if err != nil {
+		if errors.Is(err, admin.ErrUserNotFound) {
+			writeProblem(rw, r, http.StatusNotFound, codeNotFound)
+			return nil, false
+		}
+
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get user")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return nil, false
+	}
+
+	return u, true
+}
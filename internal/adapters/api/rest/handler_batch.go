@@ -0,0 +1,157 @@
+package rest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ShiraazMoollatjie/goluhn"
+	"github.com/k0st1a/gophermart/internal/pkg/order"
+	"github.com/rs/zerolog/log"
+)
+
+const maxBatchSize = 1000
+
+const (
+	batchAccepted        = "accepted"
+	batchAlreadyUploaded = "already_uploaded"
+	batchConflict        = "conflict"
+	batchInvalid         = "invalid"
+)
+
+var errBatchTooLarge = fmt.Errorf("batch contains more than %d orders", maxBatchSize)
+
+func (h *handler) createOrderBatch(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	data, ok := readBody(rw, r)
+	if !ok {
+		return
+	}
+
+	numbers, err := parseOrderBatch(r.Header.Get("Content-Type"), data)
+	if err != nil {
+		writeProblemDetail(rw, r, http.StatusBadRequest, codeValidationFailed, err.Error())
+		return
+	}
+
+	results := make([]BatchOrderResult, len(numbers))
+	orderIDs := make([]int64, 0, len(numbers))
+	seen := make(map[int64]bool, len(numbers))
+	for i, number := range numbers {
+		results[i].Number = number
+
+		if goluhn.Validate(number) != nil {
+			results[i].Result = batchInvalid
+			continue
+		}
+
+		orderID, err := strconv.ParseInt(number, 10, 64)
+		if err != nil {
+			results[i].Result = batchInvalid
+			continue
+		}
+
+		if !seen[orderID] {
+			seen[orderID] = true
+			orderIDs = append(orderIDs, orderID)
+		}
+	}
+
+	created := map[int64]error{}
+	if len(orderIDs) != 0 {
+		created, err = h.order.CreateBatch(r.Context(), userID, orderIDs)
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("create order batch error")
+			writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+			return
+		}
+	}
+
+	reported := make(map[int64]bool, len(orderIDs))
+	for i := range results {
+		if results[i].Result != "" {
+			continue
+		}
+
+		orderID, _ := strconv.ParseInt(results[i].Number, 10, 64)
+		err := created[orderID]
+		switch {
+		case err == nil && !reported[orderID]:
+			results[i].Result = batchAccepted
+		case err == nil, errors.Is(err, order.ErrAlreadyUploadedByThisUser):
+			results[i].Result = batchAlreadyUploaded
+		default:
+			results[i].Result = batchConflict
+		}
+		reported[orderID] = true
+	}
+
+	data, err = json.Marshal(&results)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize batch results")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	_, err = rw.Write(data)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write batch results")
+		return
+	}
+}
+
+// parseOrderBatch разбирает тело запроса: JSON массив номеров (строк или чисел) для application/json,
+// иначе список номеров по одному в строке. Пустые строки пропускаются.
+func parseOrderBatch(contentType string, data []byte) ([]string, error) {
+	var numbers []string
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/json" {
+		var raw []json.RawMessage
+		err := json.Unmarshal(data, &raw)
+		if err != nil {
+			return nil, fmt.Errorf("batch deserialize error:%w", err)
+		}
+
+		for _, r := range raw {
+			var number string
+			if json.Unmarshal(r, &number) != nil {
+				number = string(r)
+			}
+			numbers = append(numbers, strings.TrimSpace(number))
+		}
+	} else {
+		s := bufio.NewScanner(bytes.NewReader(data))
+		for s.Scan() {
+			number := strings.TrimSpace(s.Text())
+			if number != "" {
+				numbers = append(numbers, number)
+			}
+		}
+		if err := s.Err(); err != nil {
+			return nil, fmt.Errorf("batch read error:%w", err)
+		}
+	}
+
+	if len(numbers) == 0 {
+		return nil, errors.New("batch is empty")
+	}
+
+	if len(numbers) > maxBatchSize {
+		return nil, errBatchTooLarge
+	}
+
+	return numbers, nil
+}
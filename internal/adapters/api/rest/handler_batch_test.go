@@ -0,0 +1,73 @@
+package rest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOrderBatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		data        string
+		want        []string
+		wantErr     bool
+	}{
+		{
+			name:        "JSON strings and numbers",
+			contentType: "application/json; charset=utf-8",
+			data:        `["12345678903", 79927398713, " 9278923470 "]`,
+			want:        []string{"12345678903", "79927398713", "9278923470"},
+		},
+		{
+			name:        "Plain text with empty lines",
+			contentType: "text/plain",
+			data:        "12345678903\n\n  79927398713  \r\n",
+			want:        []string{"12345678903", "79927398713"},
+		},
+		{
+			name: "Without content type",
+			data: "12345678903",
+			want: []string{"12345678903"},
+		},
+		{
+			name:        "Invalid JSON",
+			contentType: "application/json",
+			data:        `{"number":"12345678903"}`,
+			wantErr:     true,
+		},
+		{
+			name:        "Empty JSON array",
+			contentType: "application/json",
+			data:        `[]`,
+			wantErr:     true,
+		},
+		{
+			name:        "Only empty lines",
+			contentType: "text/plain",
+			data:        "\n \n",
+			wantErr:     true,
+		},
+		{
+			name:        "Too large batch",
+			contentType: "text/plain",
+			data:        strings.Repeat("12345678903\n", maxBatchSize+1),
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			numbers, err := parseOrderBatch(test.contentType, []byte(test.data))
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, numbers)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// eventsBatchSize количество событий, читаемых из БД за один запрос.
+	eventsBatchSize = 100
+	// eventsKeepAlive период отправки комментария, чтобы прокси не закрывали простаивающее соединение.
+	eventsKeepAlive = 15 * time.Second
+)
+
+// getOrderEvents отдаёт поток Server-Sent Events об изменениях заказов и баланса пользователя.
+// Если указан заголовок Last-Event-ID, сначала отправляются события, пропущенные после него,
+// иначе только новые события.
+func (h *handler) getOrderEvents(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		log.Ctx(r.Context()).Error().Msg("streaming is not supported by response writer")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	// Подписываемся до чтения событий из БД, чтобы не пропустить уведомление между ними.
+	notify, unsubscribe := h.events.Subscribe(userID)
+	defer unsubscribe()
+
+	var lastID int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		lastID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil || lastID < 0 {
+			log.Ctx(r.Context()).Warn().Str("last_event_id", v).Msg("invalid Last-Event-ID")
+			writeProblemDetail(rw, r, http.StatusBadRequest, codeInvalidRequest, "invalid Last-Event-ID")
+			return
+		}
+	} else {
+		lastID, err = h.events.LastID(r.Context(), userID)
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("error of get last event id")
+			writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+			return
+		}
+	}
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.Header().Set("Connection", "keep-alive")
+	rw.Header().Set("X-Accel-Buffering", "no")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		lastID, err = h.writeEvents(rw, r, userID, lastID)
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("error of write events")
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-shuttingDown(r.Context()):
+			return
+		case <-notify:
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(rw, ": keep-alive\n\n")
+			if err != nil {
+				log.Ctx(r.Context()).Error().Err(err).Msg("error of write keep-alive")
+				return
+			}
+		}
+	}
+}
+
+// writeEvents пишет все события пользователя после lastID и возвращает идентификатор последнего из них.
+func (h *handler) writeEvents(rw http.ResponseWriter, r *http.Request, userID, lastID int64) (int64, error) {
+	for {
+		events, err := h.events.List(r.Context(), userID, lastID, eventsBatchSize)
+		if err != nil {
+			return lastID, fmt.Errorf("error of get events:%w", err)
+		}
+
+		for _, e := range events {
+			_, err = fmt.Fprintf(rw, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+			if err != nil {
+				return lastID, fmt.Errorf("error of write event:%w", err)
+			}
+			lastID = e.ID
+		}
+
+		if len(events) < eventsBatchSize {
+			return lastID, nil
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/pkg/health"
+	"github.com/rs/zerolog/log"
+)
+
+// readinessTimeout ограничивает суммарное время проверок готовности.
+const readinessTimeout = 3 * time.Second
+
+// Значения поля status ответов /healthz и /readyz.
+const (
+	healthAlive    = "alive"
+	healthReady    = "ready"
+	healthNotReady = "not_ready"
+	healthCheckOK  = "ok"
+	healthCheckErr = "fail"
+)
+
+// liveness отвечает 200, пока процесс способен обрабатывать запросы, и не проверяет зависимости.
+func liveness(rw http.ResponseWriter, r *http.Request) {
+	writeJSON(rw, r, http.StatusOK, &Health{Status: healthAlive})
+}
+
+// readiness выполняет проверки готовности hc и отвечает 200 с результатами, если все они пройдены,
+// иначе 503. Причины непройденных проверок только логируются: /readyz доступен без аутентификации.
+// Без проверок (hc == nil) сервис всегда готов.
+func readiness(hc *health.Health) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if hc == nil {
+			writeJSON(rw, r, http.StatusOK, &Health{Status: healthReady})
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+		defer cancel()
+
+		res := hc.Ready(ctx)
+
+		out := &Health{
+			Status: healthReady,
+			Checks: make(map[string]HealthCheck, len(res.Checks)),
+		}
+		for name, err := range res.Checks {
+			if err != nil {
+				log.Ctx(r.Context()).Warn().Err(err).Str("check", name).Msg("readiness check failed")
+				out.Checks[name] = HealthCheck{Status: healthCheckErr}
+				continue
+			}
+			out.Checks[name] = HealthCheck{Status: healthCheckOK}
+		}
+
+		status := http.StatusOK
+		if !res.Ready {
+			out.Status = healthNotReady
+			status = http.StatusServiceUnavailable
+		}
+
+		rw.Header().Set("Cache-Control", "no-store")
+		writeJSON(rw, r, status, out)
+	}
+}
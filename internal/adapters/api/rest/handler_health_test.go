@@ -0,0 +1,98 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/k0st1a/gophermart/internal/pkg/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadiness(t *testing.T) {
+	var dbErr error
+	hc := health.New()
+	hc.Add("database", func(context.Context) error { return dbErr })
+
+	h := NewHandler(nil, nil, nil, nil, nil, nil, stubProvider{}, nil, nil, nil, nil)
+	r, err := BuildRouter(h, nil, nil, nil, RouterOptions{Health: hc, DevMode: true})
+	require.NoError(t, err)
+
+	ready := func() (int, Health) {
+		rw := httptest.NewRecorder()
+		r.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/readyz", http.NoBody))
+
+		var out Health
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &out))
+		return rw.Code, out
+	}
+
+	code, out := ready()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, healthReady, out.Status)
+	assert.Equal(t, HealthCheck{Status: healthCheckOK}, out.Checks["database"])
+
+	dbErr = errors.New("connection refused")
+	code, out = ready()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, healthNotReady, out.Status)
+	assert.Equal(t, HealthCheck{Status: healthCheckErr}, out.Checks["database"])
+
+	dbErr = nil
+	hc.Shutdown()
+	code, out = ready()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, HealthCheck{Status: healthCheckOK}, out.Checks["database"])
+	assert.Equal(t, HealthCheck{Status: healthCheckErr}, out.Checks["shutdown"])
+
+	rw := httptest.NewRecorder()
+	r.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/healthz", http.NoBody))
+	assert.Equal(t, http.StatusOK, rw.Code)
+}
+
+func TestProbeRouter(t *testing.T) {
+	hc := health.New()
+	hc.Add("accrual_poller", func(context.Context) error { return errors.New("ticker is not running") })
+
+	r := BuildProbeRouter(RouterOptions{Health: hc})
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{path: "/healthz", code: http.StatusOK},
+		{path: "/readyz", code: http.StatusServiceUnavailable},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			r.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, test.path, http.NoBody))
+			assert.Equal(t, test.code, rw.Code)
+		})
+	}
+}
+
+func TestMetricsRouter(t *testing.T) {
+	r := BuildMetricsRouter(nil)
+
+	tests := []struct {
+		path string
+		code int
+	}{
+		{path: "/metrics", code: http.StatusOK},
+		{path: "/readyz", code: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			r.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, test.path, http.NoBody))
+			assert.Equal(t, test.code, rw.Code)
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package rest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/k0st1a/gophermart/internal/pkg/sso"
+	"github.com/k0st1a/gophermart/internal/pkg/user"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	oidcStateCookie  = "oidc_state"
+	oidcNonceCookie  = "oidc_nonce"
+	oidcCookiePath   = "/api/user/oidc"
+	oidcCookieMaxAge = 600
+	oidcRandomLength = 32
+)
+
+func (h *handler) oidcLogin(rw http.ResponseWriter, r *http.Request) {
+	state, err := randomString()
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of generate oidc state")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	nonce, err := randomString()
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of generate oidc nonce")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	setOIDCCookie(rw, oidcStateCookie, state, oidcCookieMaxAge)
+	setOIDCCookie(rw, oidcNonceCookie, nonce, oidcCookieMaxAge)
+
+	http.Redirect(rw, r, h.sso.AuthCodeURL(state, nonce), http.StatusFound)
+}
+
+func (h *handler) oidcCallback(rw http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	state, err := r.Cookie(oidcStateCookie)
+	if err != nil || state.Value != q.Get("state") {
+		log.Ctx(r.Context()).Warn().Msg("OIDC state mismatch")
+		writeProblem(rw, r, http.StatusBadRequest, codeOIDCFailed)
+		return
+	}
+
+	nonce, err := r.Cookie(oidcNonceCookie)
+	if err != nil {
+		log.Ctx(r.Context()).Warn().Msg("OIDC nonce cookie not set")
+		writeProblem(rw, r, http.StatusBadRequest, codeOIDCFailed)
+		return
+	}
+
+	setOIDCCookie(rw, oidcStateCookie, "", -1)
+	setOIDCCookie(rw, oidcNonceCookie, "", -1)
+
+	if e := q.Get("error"); e != "" {
+		log.Ctx(r.Context()).Warn().
+			Str("error", e).
+			Str("error_description", q.Get("error_description")).
+			Msg("OIDC provider error")
+		writeProblem(rw, r, http.StatusUnauthorized, codeOIDCFailed)
+		return
+	}
+
+	identity, err := h.sso.Exchange(r.Context(), q.Get("code"), nonce.Value)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of oidc exchange")
+		writeProblem(rw, r, http.StatusUnauthorized, codeOIDCFailed)
+		return
+	}
+
+	userID, err := h.getOrCreateOIDCUser(r.Context(), identity)
+	if errors.Is(err, user.ErrLocked) {
+		writeProblem(rw, r, http.StatusForbidden, codeAccountLocked)
+		return
+	}
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get or create oidc user")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	t, err := h.issuer.Issue(r.Context(), userID, clientOf(r))
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of issue token")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.Header().Set("Authorization", t)
+	rw.WriteHeader(http.StatusOK)
+}
+
+// getOrCreateOIDCUser возвращает пользователя, привязанного к identity, либо создаёт нового.
+// Существующий локальный пользователь с тем же логином не привязывается: иначе владелец такого логина
+// у провайдера получил бы доступ к чужому аккаунту.
+func (h *handler) getOrCreateOIDCUser(ctx context.Context, identity *sso.Identity) (int64, error) {
+	userID, err := h.user.GetIDByIdentity(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		return userID, nil
+	}
+	if !errors.Is(err, user.ErrNotFound) {
+		return 0, fmt.Errorf("error of get user id by identity:%w", err)
+	}
+
+	// Пароль случайный и нигде не сохраняется, войти по логину и паролю такой пользователь не сможет.
+	password, err := randomString()
+	if err != nil {
+		return 0, fmt.Errorf("error of generate password:%w", err)
+	}
+
+	passwordHash, err := h.auth.GeneratePasswordHash(password)
+	if err != nil {
+		return 0, fmt.Errorf("error of generate password hash:%w", err)
+	}
+
+	userID, err = h.user.CreateWithIdentity(ctx, identity.Login, passwordHash, identity.Issuer, identity.Subject)
+	if errors.Is(err, user.ErrIdentityLinked) {
+		// Параллельный вход того же пользователя успел создать пользователя и привязать identity раньше,
+		// транзакция этого входа откатилась вместе с созданным пользователем.
+		//nolint:wrapcheck //already wrapped
+		return h.user.GetIDByIdentity(ctx, identity.Issuer, identity.Subject)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error of create user with identity:%w", err)
+	}
+	log.Ctx(ctx).Info().
+		Int64("created_user_id", userID).
+		Str("issuer", identity.Issuer).
+		Str("subject", identity.Subject).
+		Msg("created user by OIDC identity")
+
+	return userID, nil
+}
+
+func setOIDCCookie(rw http.ResponseWriter, name, value string, maxAge int) {
+	http.SetCookie(rw, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     oidcCookiePath,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func randomString() (string, error) {
+	b := make([]byte, oidcRandomLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to read random:%w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,87 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/k0st1a/gophermart/internal/pkg/session"
+	"github.com/rs/zerolog/log"
+)
+
+func (h *handler) getSessions(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	currentID, err := getSessionID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	sessions, err := h.session.List(r.Context(), userID)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get sessions")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	modelSessions := make([]Session, len(sessions))
+	for i, s := range sessions {
+		modelSessions[i] = Session{
+			ID:         s.ID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastSeenAt: s.LastSeenAt,
+			Current:    s.ID == currentID,
+		}
+	}
+
+	data, err := json.Marshal(&modelSessions)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize sessions")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	_, err = rw.Write(data)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write sessions")
+		return
+	}
+}
+
+func (h *handler) terminateSession(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	sessionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidRequest)
+		return
+	}
+
+	err = h.session.Terminate(r.Context(), userID, sessionID)
+	if err != nil {
+		if errors.Is(err, session.ErrNotFound) {
+			writeProblem(rw, r, http.StatusNotFound, codeNotFound)
+			return
+		}
+
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of terminate session")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,257 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/k0st1a/gophermart/internal/pkg/auth"
+	"github.com/k0st1a/gophermart/internal/pkg/export"
+	"github.com/k0st1a/gophermart/internal/pkg/order"
+	"github.com/k0st1a/gophermart/internal/pkg/user"
+	"github.com/k0st1a/gophermart/internal/pkg/withdraw"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubWithdraw struct {
+	withdraw.Managment
+	sums []float64
+}
+
+func (s *stubWithdraw) Create(_ context.Context, _, _ int64, sum float64) error {
+	s.sums = append(s.sums, sum)
+	return nil
+}
+
+func TestCreateWithdrawSum(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		code int
+	}{
+		{name: "Positive", body: `{"order":"2377225624","sum":751}`, code: http.StatusOK},
+		{name: "Negative", body: `{"order":"2377225624","sum":-100}`, code: http.StatusUnprocessableEntity},
+		{name: "Zero", body: `{"order":"2377225624","sum":0}`, code: http.StatusUnprocessableEntity},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := &stubWithdraw{}
+			h := NewHandler(nil, nil, nil, w, nil, nil, nil, nil, nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/user/balance/withdraw", strings.NewReader(test.body))
+			req = req.WithContext(context.WithValue(req.Context(), ctxUserID{}, int64(1)))
+			rw := httptest.NewRecorder()
+
+			h.createWithdraw(rw, req)
+
+			assert.Equal(t, test.code, rw.Code)
+			if test.code != http.StatusOK {
+				assert.Empty(t, w.sums)
+			}
+		})
+	}
+}
+
+func TestCreateWithdrawalV2(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		code   int
+		detail string
+	}{
+		{name: "Valid", body: `{"order":"2377225624","sum":"751.25"}`, code: http.StatusNoContent},
+		{
+			name:   "Invalid amount",
+			body:   `{"order":"2377225624","sum":"1e3"}`,
+			code:   http.StatusBadRequest,
+			detail: errInvalidAmount.Error(),
+		},
+		{name: "Wrong field type", body: `{"order":2377225624,"sum":"751.25"}`, code: http.StatusBadRequest},
+		{name: "Not JSON", body: `sum=751.25`, code: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := &stubWithdraw{}
+			h := NewHandler(nil, nil, nil, w, nil, nil, nil, nil, nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v2/user/withdrawals", strings.NewReader(test.body))
+			req = req.WithContext(context.WithValue(req.Context(), ctxUserID{}, int64(1)))
+			rw := httptest.NewRecorder()
+
+			h.createWithdrawalV2(rw, req)
+
+			assert.Equal(t, test.code, rw.Code)
+			if test.code == http.StatusNoContent {
+				assert.Equal(t, []float64{751.25}, w.sums)
+				return
+			}
+			var p Problem
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &p))
+			assert.Equal(t, codeInvalidRequest, p.Code)
+			// Детали разбора JSON (типы и поля Go) клиенту не раскрываются.
+			assert.Equal(t, test.detail, p.Detail)
+			assert.Empty(t, w.sums)
+		})
+	}
+}
+
+type stubUser struct {
+	user.Managment
+	locked bool
+}
+
+func (s *stubUser) GetIDAndPassword(_ context.Context, _ string) (int64, string, bool, error) {
+	return 1, "hash:secret", s.locked, nil
+}
+
+type stubAuth struct {
+	auth.UserAuthentication
+}
+
+func (stubAuth) CheckPasswordHash(password, hash string) error {
+	if hash != "hash:"+password {
+		return errors.New("password mismatch")
+	}
+	return nil
+}
+
+// TestLoginLockedUser проверяет, что о блокировке аккаунта узнаёт только тот, кто знает пароль.
+func TestLoginLockedUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		code     int
+		problem  string
+	}{
+		{name: "Wrong password", password: "guess", code: http.StatusConflict, problem: codeInvalidCredentials},
+		{name: "Right password", password: "secret", code: http.StatusForbidden, problem: codeAccountLocked},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHandler(stubAuth{}, &stubUser{locked: true}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+			body := `{"login":"alice","password":"` + test.password + `"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/user/login", strings.NewReader(body))
+			rw := httptest.NewRecorder()
+
+			h.login(rw, req)
+
+			assert.Equal(t, test.code, rw.Code)
+			var p Problem
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &p))
+			assert.Equal(t, test.problem, p.Code)
+		})
+	}
+}
+
+type stubExport struct {
+	export.Managment
+	err error
+}
+
+func (s stubExport) Export(_ context.Context, _ int64) (*export.Archive, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &export.Archive{Login: "alice", Role: auth.RoleUser}, nil
+}
+
+func TestExportUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		code    int
+		problem string
+	}{
+		{name: "Exported", code: http.StatusOK},
+		{name: "User not found", err: export.ErrUserNotFound, code: http.StatusNotFound, problem: codeNotFound},
+		{name: "Storage error", err: errors.New("connection refused"), code: http.StatusInternalServerError,
+			problem: codeInternal},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHandler(nil, nil, nil, nil, nil, nil, nil, stubExport{err: test.err}, nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/export", http.NoBody)
+			req = req.WithContext(context.WithValue(req.Context(), ctxUserID{}, int64(1)))
+			rw := httptest.NewRecorder()
+
+			h.exportUser(rw, req)
+
+			assert.Equal(t, test.code, rw.Code)
+			if test.code == http.StatusOK {
+				var e Export
+				require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &e))
+				assert.Equal(t, "alice", e.Login)
+				return
+			}
+			var p Problem
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &p))
+			assert.Equal(t, test.problem, p.Code)
+		})
+	}
+}
+
+type stubOrder struct {
+	order.Managment
+}
+
+func (stubOrder) Get(_ context.Context, userID, orderID int64) (*order.Details, error) {
+	if userID != 1 || orderID != 12345678903 {
+		return nil, order.ErrNotFound
+	}
+	return &order.Details{
+		Order: order.Order{Number: orderID, Status: "PROCESSED", Accrual: 500},
+		Polls: []order.Poll{{Status: "PROCESSED", Accrual: 500}},
+	}, nil
+}
+
+func TestGetOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		number  string
+		code    int
+		problem string
+	}{
+		{name: "Own order", number: "12345678903", code: http.StatusOK},
+		{name: "Unknown order", number: "9278923470", code: http.StatusNotFound, problem: codeNotFound},
+		{name: "Invalid number", number: "12ab", code: http.StatusBadRequest, problem: codeInvalidOrderNumber},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHandler(nil, nil, stubOrder{}, nil, nil, nil, nil, nil, nil, nil, nil)
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("number", test.number)
+			req := httptest.NewRequest(http.MethodGet, "/api/user/orders/"+test.number, http.NoBody)
+			ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+			req = req.WithContext(context.WithValue(ctx, ctxUserID{}, int64(1)))
+			rw := httptest.NewRecorder()
+
+			h.getOrder(rw, req)
+
+			assert.Equal(t, test.code, rw.Code)
+			if test.code == http.StatusOK {
+				var od OrderDetails
+				require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &od))
+				assert.Equal(t, int64(12345678903), od.Number)
+				assert.Len(t, od.Polls, 1)
+				return
+			}
+			var p Problem
+			require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &p))
+			assert.Equal(t, test.problem, p.Code)
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/k0st1a/gophermart/internal/pkg/order"
+	"github.com/k0st1a/gophermart/internal/pkg/withdraw"
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+func (h *handler) registerV2(rw http.ResponseWriter, r *http.Request) {
+	t, ok := h.registerUser(rw, r)
+	if !ok {
+		return
+	}
+
+	rw.Header().Set("Authorization", t)
+	writeJSON(rw, r, http.StatusOK, TokenV2{Token: t})
+}
+
+func (h *handler) loginV2(rw http.ResponseWriter, r *http.Request) {
+	t, ok := h.loginUser(rw, r)
+	if !ok {
+		return
+	}
+
+	rw.Header().Set("Authorization", t)
+	writeJSON(rw, r, http.StatusOK, TokenV2{Token: t})
+}
+
+func (h *handler) createOrderV2(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	data, ok := readBody(rw, r)
+	if !ok {
+		return
+	}
+
+	var in OrderInV2
+	err = json.Unmarshal(data, &in)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("order deserialize error")
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidRequest)
+		return
+	}
+
+	orderID, status, ok := h.uploadOrder(rw, r, userID, in.Number)
+	if !ok {
+		return
+	}
+
+	d, err := h.order.Get(r.Context(), userID, orderID)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get order")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	writeJSON(rw, r, status, toOrderV2(&d.Order))
+}
+
+//nolint:dupl //similar to getWithdrawalsV2
+func (h *handler) getOrdersV2(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	filter, err := parseListFilter(r, true, ports.DefaultListLimit)
+	if err != nil {
+		writeProblemDetail(rw, r, http.StatusBadRequest, codeInvalidListParam, err.Error())
+		return
+	}
+
+	orders, err := h.order.List(r.Context(), userID, filter)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get orders")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	list := ListV2[OrderV2]{Items: make([]OrderV2, len(orders))}
+	for i := range orders {
+		list.Items[i] = toOrderV2(&orders[i])
+	}
+	if filter.Limit > 0 && len(orders) == filter.Limit {
+		list.NextCursor = encodeCursor(orders[len(orders)-1].Number)
+	}
+
+	writeJSON(rw, r, http.StatusOK, list)
+}
+
+func (h *handler) getOrderV2(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	d, ok := h.lookupOrder(rw, r, userID)
+	if !ok {
+		return
+	}
+
+	od := OrderDetailsV2{
+		OrderV2: toOrderV2(&d.Order),
+		Polls:   make([]OrderPollV2, len(d.Polls)),
+	}
+	for i, p := range d.Polls {
+		od.Polls[i] = OrderPollV2{
+			PolledAt: p.PolledAt,
+			Status:   p.Status,
+		}
+		if p.Accrual != 0 {
+			a := newAmount(p.Accrual)
+			od.Polls[i].Accrual = &a
+		}
+	}
+
+	writeJSON(rw, r, http.StatusOK, od)
+}
+
+func (h *handler) getBalanceV2(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	current, withdrawn, err := h.user.GetBalance(r.Context(), userID)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get balance")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	writeJSON(rw, r, http.StatusOK, BalanceV2{
+		Current:   newAmount(current),
+		Withdrawn: newAmount(withdrawn),
+	})
+}
+
+func (h *handler) createWithdrawalV2(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	data, ok := readBody(rw, r)
+	if !ok {
+		return
+	}
+
+	var w WithdrawInV2
+	err = json.Unmarshal(data, &w)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("withdraw deserialize error")
+		if errors.Is(err, errInvalidAmount) {
+			writeProblemDetail(rw, r, http.StatusBadRequest, codeInvalidRequest, errInvalidAmount.Error())
+			return
+		}
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidRequest)
+		return
+	}
+
+	if !h.withdrawPoints(rw, r, userID, w.Order, w.Sum.Float64()) {
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+//nolint:dupl //similar to getOrdersV2
+func (h *handler) getWithdrawalsV2(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	filter, err := parseListFilter(r, false, ports.DefaultListLimit)
+	if err != nil {
+		writeProblemDetail(rw, r, http.StatusBadRequest, codeInvalidListParam, err.Error())
+		return
+	}
+
+	withdrawals, err := h.withdraw.List(r.Context(), userID, filter)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get withdrawals")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	list := ListV2[WithdrawalV2]{Items: make([]WithdrawalV2, len(withdrawals))}
+	for i := range withdrawals {
+		list.Items[i] = toWithdrawalV2(&withdrawals[i])
+	}
+	if filter.Limit > 0 && len(withdrawals) == filter.Limit {
+		list.NextCursor = encodeCursor(withdrawals[len(withdrawals)-1].ID)
+	}
+
+	writeJSON(rw, r, http.StatusOK, list)
+}
+
+func toOrderV2(o *order.Order) OrderV2 {
+	out := OrderV2{
+		UploadedAt: o.UploadedAt,
+		Number:     strconv.FormatInt(o.Number, 10),
+		Status:     o.Status,
+	}
+	if o.Status == order.StatusProcessed {
+		a := newAmount(o.Accrual)
+		out.Accrual = &a
+	}
+
+	return out
+}
+
+func toWithdrawalV2(w *withdraw.Withdraw) WithdrawalV2 {
+	return WithdrawalV2{
+		ProcessedAt: w.ProcessedAt,
+		Order:       strconv.FormatInt(w.Order, 10),
+		Sum:         newAmount(w.Sum),
+	}
+}
+
+// writeJSON пишет ответ с телом v в формате JSON.
+func writeJSON(rw http.ResponseWriter, r *http.Request, status int, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Type("type", v).Msg("error of serialize")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_, err = rw.Write(data)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Type("type", v).Msg("error of write")
+		return
+	}
+}
+
+// deprecated помечает ответы маршрута API v1 как устаревшие (заголовок Deprecation) и указывает замену
+// в API v2 (заголовок Link с rel="successor-version"), если она есть. Параметры пути в successor,
+// например {number}, подставляются из запроса.
+func deprecated(successor string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.Header().Set("Deprecation", "true")
+			if successor == "" {
+				next.ServeHTTP(rw, r)
+				return
+			}
+
+			link := successor
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				for i, k := range rctx.URLParams.Keys {
+					link = strings.ReplaceAll(link, "{"+k+"}", url.PathEscape(rctx.URLParams.Values[i]))
+				}
+			}
+
+			rw.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, link))
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/k0st1a/gophermart/internal/pkg/webhook"
+	"github.com/rs/zerolog/log"
+)
+
+func (h *handler) createWebhook(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	data, ok := readBody(rw, r)
+	if !ok {
+		return
+	}
+
+	var wi WebhookIn
+	err = json.Unmarshal(data, &wi)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("webhook deserialize error")
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidRequest)
+		return
+	}
+
+	w, secret, err := h.webhook.Create(r.Context(), userID, wi.URL, wi.Events)
+	if err != nil {
+		switch {
+		case errors.Is(err, webhook.ErrInvalidURL),
+			errors.Is(err, webhook.ErrEmptyEvents),
+			errors.Is(err, webhook.ErrUnknownEvent):
+			writeProblemDetail(rw, r, http.StatusBadRequest, codeValidationFailed, err.Error())
+			return
+		default:
+			log.Ctx(r.Context()).Error().Err(err).Msg("error of create webhook")
+			writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+			return
+		}
+	}
+
+	out := toWebhookOut(w)
+	out.Secret = secret
+
+	data, err = json.Marshal(&out)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize webhook")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusCreated)
+	_, err = rw.Write(data)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write webhook")
+		return
+	}
+}
+
+func (h *handler) getWebhooks(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	webhooks, err := h.webhook.List(r.Context(), userID)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get webhooks")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	if len(webhooks) == 0 {
+		rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	modelWebhooks := make([]WebhookOut, len(webhooks))
+	for i := range webhooks {
+		modelWebhooks[i] = toWebhookOut(&webhooks[i])
+	}
+
+	data, err := json.Marshal(&modelWebhooks)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize webhooks")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	_, err = rw.Write(data)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write webhooks")
+		return
+	}
+}
+
+func (h *handler) deleteWebhook(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	webhookID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidRequest)
+		return
+	}
+
+	err = h.webhook.Delete(r.Context(), userID, webhookID)
+	if err != nil {
+		if errors.Is(err, webhook.ErrNotFound) {
+			writeProblem(rw, r, http.StatusNotFound, codeNotFound)
+			return
+		}
+
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of delete webhook")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) getWebhookDeliveries(rw http.ResponseWriter, r *http.Request) {
+	userID, err := getUserID(r.Context())
+	if err != nil {
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	webhookID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		writeProblem(rw, r, http.StatusBadRequest, codeInvalidRequest)
+		return
+	}
+
+	deliveries, err := h.webhook.Deliveries(r.Context(), userID, webhookID)
+	if err != nil {
+		if errors.Is(err, webhook.ErrNotFound) {
+			writeProblem(rw, r, http.StatusNotFound, codeNotFound)
+			return
+		}
+
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of get webhook deliveries")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	if len(deliveries) == 0 {
+		rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	modelDeliveries := make([]WebhookDelivery, len(deliveries))
+	for i, d := range deliveries {
+		modelDeliveries[i] = WebhookDelivery{
+			ID:             d.ID,
+			Event:          d.EventType,
+			Payload:        d.Payload,
+			Status:         d.Status,
+			Attempts:       d.Attempts,
+			CreatedAt:      d.CreatedAt,
+			NextAttemptAt:  d.NextAttemptAt,
+			LastAttemptAt:  d.LastAttemptAt,
+			DeliveredAt:    d.DeliveredAt,
+			LastStatusCode: d.LastStatusCode,
+			LastError:      d.LastError,
+		}
+	}
+
+	data, err := json.Marshal(&modelDeliveries)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize webhook deliveries")
+		writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	_, err = rw.Write(data)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write webhook deliveries")
+		return
+	}
+}
+
+func toWebhookOut(w *webhook.Webhook) WebhookOut {
+	return WebhookOut{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.Events,
+		CreatedAt: w.CreatedAt,
+	}
+}
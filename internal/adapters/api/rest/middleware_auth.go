@@ -4,38 +4,151 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
+	"github.com/k0st1a/gophermart/internal/pkg/apikey"
 	"github.com/k0st1a/gophermart/internal/pkg/auth"
+	"github.com/k0st1a/gophermart/internal/pkg/logging"
+	"github.com/k0st1a/gophermart/internal/pkg/session"
+	"github.com/k0st1a/gophermart/internal/pkg/user"
 	"github.com/rs/zerolog/log"
 )
 
 type ctxUserID struct{}
+type ctxRole struct{}
+type ctxAPIKey struct{}
+type ctxSessionID struct{}
 
-func authenticate(auth auth.UserAuthentication) func(next http.Handler) http.Handler {
+func authenticate(auth auth.UserAuthentication, users user.Managment, keys apikey.Managment,
+	sessions session.Managment) func(next http.Handler) http.Handler {
 	// Подсмотрено в https://github.com/go-chi/chi/blob/master/middleware/content_type.go
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if key := r.Header.Get("X-API-Key"); key != "" {
+				authenticateAPIKey(keys, next, rw, r, key)
+				return
+			}
+
 			ah := r.Header.Get("Authorization")
 			if ah == "" {
-				log.Printf("Authorization header not set")
-				rw.WriteHeader(http.StatusUnauthorized)
+				log.Ctx(r.Context()).Warn().Msg("authorization header not set")
+				writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+				return
+			}
+
+			claims, err := auth.GetClaims(strings.TrimPrefix(ah, "Bearer "))
+			if err != nil {
+				log.Ctx(r.Context()).Error().Err(err).Msg("error of get claims")
+				writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
 				return
 			}
 
-			userID, err := auth.GetUserID(ah)
+			err = sessions.Check(r.Context(), claims.UserID, claims.SessionID)
 			if err != nil {
-				log.Error().Err(err).Msg("error of get userID")
-				rw.WriteHeader(http.StatusUnauthorized)
+				log.Ctx(r.Context()).Error().Err(err).Msg("error of check session")
+				writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
 				return
 			}
 
-			ctx := context.WithValue(r.Context(), ctxUserID{}, userID)
-			log.Printf("Authentication UserID:%v", userID)
+			err = users.CheckActive(r.Context(), claims.UserID)
+			if err != nil {
+				log.Ctx(r.Context()).Error().Err(err).Msg("error of check user active")
+				writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ctxUserID{}, claims.UserID)
+			ctx = context.WithValue(ctx, ctxRole{}, claims.Role)
+			ctx = context.WithValue(ctx, ctxSessionID{}, claims.SessionID)
+			ctx = logging.SetUserID(ctx, claims.UserID)
+			log.Ctx(ctx).Debug().Str("role", claims.Role).Int64("session_id", claims.SessionID).Msg("authenticated")
 			next.ServeHTTP(rw, r.WithContext(ctx))
 		})
 	}
 }
 
+// authenticateAPIKey аутентифицирует интеграцию по API ключу. Запрос выполняется от имени владельца ключа
+// с ролью auth.RoleUser и ограничивается областями (scopes) ключа, см. requireScope.
+func authenticateAPIKey(keys apikey.Managment, next http.Handler, rw http.ResponseWriter, r *http.Request,
+	key string) {
+	k, err := keys.Authenticate(r.Context(), key)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of authenticate api key")
+		writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+		return
+	}
+
+	ctx := logging.SetUserID(r.Context(), k.UserID)
+	log.Ctx(ctx).Info().
+		Int64("api_key_id", k.ID).
+		Str("api_key_name", k.Name).
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Msg("API key request")
+
+	ctx = context.WithValue(ctx, ctxUserID{}, k.UserID)
+	ctx = context.WithValue(ctx, ctxRole{}, auth.RoleUser)
+	ctx = context.WithValue(ctx, ctxAPIKey{}, k)
+	next.ServeHTTP(rw, r.WithContext(ctx))
+}
+
+// authorize пропускает запрос только если роль аутентифицированного пользователя входит в roles.
+// Должен использоваться после authenticate.
+func authorize(roles ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			role, err := getRole(r.Context())
+			if err != nil {
+				writeProblem(rw, r, http.StatusUnauthorized, codeUnauthorized)
+				return
+			}
+
+			if !slices.Contains(roles, role) {
+				log.Ctx(r.Context()).Warn().Str("role", role).Strs("allowed_roles", roles).Msg("authorization failed")
+				writeProblem(rw, r, http.StatusForbidden, codeForbidden)
+				return
+			}
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// requireScope ограничивает запросы, аутентифицированные по API ключу, областью scope.
+// Запросы с JWT токеном пользователя пропускаются без ограничений.
+func requireScope(scope string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			k, ok := r.Context().Value(ctxAPIKey{}).(*apikey.Key)
+			if ok && !k.HasScope(scope) {
+				log.Ctx(r.Context()).Warn().Int64("api_key_id", k.ID).Str("scope", scope).Msg("API key has no scope")
+				writeProblem(rw, r, http.StatusForbidden, codeForbidden)
+				return
+			}
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// userTokenOnly запрещает доступ по API ключу. Используется для операций над самим аккаунтом.
+func userTokenOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if k, ok := r.Context().Value(ctxAPIKey{}).(*apikey.Key); ok {
+			log.Ctx(r.Context()).Warn().
+				Int64("api_key_id", k.ID).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Msg("API key not allowed")
+			writeProblem(rw, r, http.StatusForbidden, codeForbidden)
+			return
+		}
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
 func getUserID(ctx context.Context) (int64, error) {
 	userID, ok := ctx.Value(ctxUserID{}).(int64)
 	if !ok {
@@ -43,3 +156,19 @@ func getUserID(ctx context.Context) (int64, error) {
 	}
 	return userID, nil
 }
+
+func getSessionID(ctx context.Context) (int64, error) {
+	sessionID, ok := ctx.Value(ctxSessionID{}).(int64)
+	if !ok {
+		return 0, fmt.Errorf("session id not found in context")
+	}
+	return sessionID, nil
+}
+
+func getRole(ctx context.Context) (string, error) {
+	role, ok := ctx.Value(ctxRole{}).(string)
+	if !ok {
+		return "", fmt.Errorf("role not found in context")
+	}
+	return role, nil
+}
@@ -0,0 +1,183 @@
+package rest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/k0st1a/gophermart/internal/pkg/apikey"
+	"github.com/k0st1a/gophermart/internal/pkg/auth"
+	"github.com/k0st1a/gophermart/internal/pkg/session"
+	"github.com/k0st1a/gophermart/internal/pkg/user"
+	"github.com/stretchr/testify/assert"
+)
+
+// okHandler отвечает 200, если запрос прошёл проверяемый middleware.
+var okHandler = http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+	rw.WriteHeader(http.StatusOK)
+})
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name  string
+		role  string
+		roles []string
+		code  int
+	}{
+		{name: "Admin on admin route", role: auth.RoleAdmin, roles: []string{auth.RoleAdmin}, code: http.StatusOK},
+		{
+			name:  "Support on support route",
+			role:  auth.RoleSupport,
+			roles: []string{auth.RoleSupport, auth.RoleAdmin},
+			code:  http.StatusOK,
+		},
+		{name: "User on admin route", role: auth.RoleUser, roles: []string{auth.RoleAdmin}, code: http.StatusForbidden},
+		{
+			name:  "Support on admin route",
+			role:  auth.RoleSupport,
+			roles: []string{auth.RoleAdmin},
+			code:  http.StatusForbidden,
+		},
+		{name: "Not authenticated", roles: []string{auth.RoleAdmin}, code: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/users", http.NoBody)
+			if test.role != "" {
+				req = req.WithContext(context.WithValue(req.Context(), ctxRole{}, test.role))
+			}
+			rw := httptest.NewRecorder()
+
+			authorize(test.roles...)(okHandler).ServeHTTP(rw, req)
+
+			assert.Equal(t, test.code, rw.Code)
+		})
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	key := &apikey.Key{ID: 1, Scopes: []string{apikey.ScopeOrdersRead}}
+
+	tests := []struct {
+		name  string
+		key   *apikey.Key
+		scope string
+		code  int
+	}{
+		{name: "API key with scope", key: key, scope: apikey.ScopeOrdersRead, code: http.StatusOK},
+		{name: "API key without scope", key: key, scope: apikey.ScopeOrdersWrite, code: http.StatusForbidden},
+		{name: "User token", scope: apikey.ScopeOrdersWrite, code: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/user/orders", http.NoBody)
+			if test.key != nil {
+				req = req.WithContext(context.WithValue(req.Context(), ctxAPIKey{}, test.key))
+			}
+			rw := httptest.NewRecorder()
+
+			requireScope(test.scope)(okHandler).ServeHTTP(rw, req)
+
+			assert.Equal(t, test.code, rw.Code)
+		})
+	}
+}
+
+func TestUserTokenOnly(t *testing.T) {
+	tests := []struct {
+		name string
+		key  *apikey.Key
+		code int
+	}{
+		{name: "API key", key: &apikey.Key{ID: 1, Scopes: apikey.Scopes}, code: http.StatusForbidden},
+		{name: "User token", code: http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/user/apikeys", http.NoBody)
+			if test.key != nil {
+				req = req.WithContext(context.WithValue(req.Context(), ctxAPIKey{}, test.key))
+			}
+			rw := httptest.NewRecorder()
+
+			userTokenOnly(okHandler).ServeHTTP(rw, req)
+
+			assert.Equal(t, test.code, rw.Code)
+		})
+	}
+}
+
+// stubClaims принимает любой токен, кроме "invalid", как токен пользователя 1 с сессией 7.
+type stubClaims struct {
+	auth.UserAuthentication
+}
+
+func (stubClaims) GetClaims(token string) (*auth.Claims, error) {
+	if token == "invalid" {
+		return nil, errors.New("token not valid")
+	}
+	return &auth.Claims{UserID: 1, SessionID: 7, Role: auth.RoleUser}, nil
+}
+
+type stubSessions struct {
+	session.Managment
+	terminated bool
+}
+
+func (s stubSessions) Check(_ context.Context, _, _ int64) error {
+	if s.terminated {
+		return session.ErrNotFound
+	}
+	return nil
+}
+
+type stubActiveUser struct {
+	user.Managment
+}
+
+func (stubActiveUser) CheckActive(_ context.Context, _ int64) error {
+	return nil
+}
+
+func TestAuthenticateSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		terminated bool
+		code       int
+	}{
+		{name: "Active session", token: "Bearer token", code: http.StatusOK},
+		{name: "Terminated session", token: "Bearer token", terminated: true, code: http.StatusUnauthorized},
+		{name: "Invalid token", token: "Bearer invalid", code: http.StatusUnauthorized},
+		{name: "No token", code: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sessionID int64
+			next := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				sessionID, _ = r.Context().Value(ctxSessionID{}).(int64)
+				rw.WriteHeader(http.StatusOK)
+			})
+			mw := authenticate(stubClaims{}, stubActiveUser{}, nil, stubSessions{terminated: test.terminated})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/user/balance", http.NoBody)
+			if test.token != "" {
+				req.Header.Set("Authorization", test.token)
+			}
+			rw := httptest.NewRecorder()
+
+			mw(next).ServeHTTP(rw, req)
+
+			assert.Equal(t, test.code, rw.Code)
+			if test.code == http.StatusOK {
+				assert.Equal(t, int64(7), sessionID)
+			}
+		})
+	}
+}
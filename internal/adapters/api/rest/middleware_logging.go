@@ -0,0 +1,37 @@
+package rest
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/k0st1a/gophermart/internal/pkg/logging"
+	"github.com/rs/zerolog/log"
+)
+
+// logRequests добавляет в контекст запроса логгер с идентификатором запроса (см. middleware.RequestID),
+// через который пишут все слои сервиса, и по завершении запроса пишет его в журнал. Идентификатор
+// пользователя добавляется к логгеру при аутентификации, поэтому попадает и в журнал запросов.
+func logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := logging.WithRequestID(r.Context(), middleware.GetReqID(r.Context()))
+		ww := middleware.NewWrapResponseWriter(rw, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		log.Ctx(ctx).Info().
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Str("remote_addr", r.RemoteAddr).
+			Int("status", status).
+			Int("bytes", ww.BytesWritten()).
+			Dur("duration", time.Since(start)).
+			Msg("request")
+	})
+}
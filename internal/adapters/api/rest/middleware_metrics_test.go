@@ -0,0 +1,38 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/k0st1a/gophermart/internal/pkg/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrument(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	require.NoError(t, reg.Register(metrics.HTTPRequests))
+
+	r := newTestRouter(t, false)
+	for _, path := range []string{"/api/user/orders/12345678903", "/api/user/orders/79927398713", "/unknown"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, http.NoBody))
+	}
+
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+	require.Len(t, mfs, 1)
+
+	got := make(map[string]float64)
+	for _, m := range mfs[0].GetMetric() {
+		labels := make(map[string]string)
+		for _, l := range m.GetLabel() {
+			labels[l.GetName()] = l.GetValue()
+		}
+		got[labels["method"]+" "+labels["route"]+" "+labels["status"]] = m.GetCounter().GetValue()
+	}
+
+	assert.Equal(t, float64(2), got["GET /api/user/orders/{number} 401"])
+	assert.Equal(t, float64(1), got["GET unmatched 404"])
+}
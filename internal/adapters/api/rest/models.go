@@ -1,6 +1,9 @@
 package rest
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Register struct {
 	Login    string `json:"login"`
@@ -29,8 +32,133 @@ type Order struct {
 	Accrual    float64   `json:"accrual,omitempty"`
 }
 
+type OrderDetails struct {
+	Order
+	Polls []OrderPoll `json:"polls"`
+}
+
+type OrderPoll struct {
+	PolledAt time.Time `json:"polled_at"`
+	Status   string    `json:"status"`
+	Accrual  float64   `json:"accrual,omitempty"`
+}
+
 type WithdrawOut struct {
 	ProcessedAt time.Time `json:"processed_at"`
+	ID          int64     `json:"-"`
 	Order       int64     `json:"order,string"`
 	Sum         float64   `json:"sum"`
 }
+
+type AdminUser struct {
+	Login     string  `json:"login"`
+	Role      string  `json:"role"`
+	ID        int64   `json:"id"`
+	Balance   float64 `json:"balance"`
+	Withdrawn float64 `json:"withdrawn"`
+}
+
+type BalanceAdjustment struct {
+	Reason string  `json:"reason"`
+	Amount float64 `json:"amount"`
+}
+
+type APIKeyIn struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+//nolint:govet //incorrectly detects alignment
+type APIKeyOut struct {
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Name       string     `json:"name"`
+	Key        string     `json:"key,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	ID         int64      `json:"id"`
+}
+
+//nolint:govet //incorrectly detects alignment
+type Export struct {
+	ExportedAt     time.Time      `json:"exported_at"`
+	Login          string         `json:"login"`
+	Role           string         `json:"role"`
+	Balance        Balance        `json:"balance"`
+	Orders         []Order        `json:"orders"`
+	Withdrawals    []WithdrawOut  `json:"withdrawals"`
+	BalanceHistory []BalanceEntry `json:"balance_history"`
+}
+
+type BalanceEntry struct {
+	At     time.Time `json:"at"`
+	Type   string    `json:"type"`
+	Reason string    `json:"reason,omitempty"`
+	Order  int64     `json:"order,string,omitempty"`
+	Amount float64   `json:"amount"`
+}
+
+type Session struct {
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	ID         int64     `json:"id"`
+	Current    bool      `json:"current"`
+}
+
+type BatchOrderResult struct {
+	Number string `json:"number"`
+	Result string `json:"result"`
+}
+
+type WebhookIn struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+//nolint:govet //incorrectly detects alignment
+type WebhookOut struct {
+	CreatedAt time.Time `json:"created_at"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	ID        int64     `json:"id"`
+}
+
+//nolint:govet //incorrectly detects alignment
+type WebhookDelivery struct {
+	CreatedAt      time.Time       `json:"created_at"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	LastStatusCode *int            `json:"last_status_code,omitempty"`
+	Event          string          `json:"event"`
+	Status         string          `json:"status"`
+	LastError      string          `json:"last_error,omitempty"`
+	Payload        json.RawMessage `json:"payload"`
+	ID             int64           `json:"id"`
+	Attempts       int             `json:"attempts"`
+}
+
+// Health тело ответа /healthz и /readyz.
+type Health struct {
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+	Status string                 `json:"status"`
+}
+
+// HealthCheck результат отдельной проверки готовности.
+type HealthCheck struct {
+	Status string `json:"status"`
+}
+
+// Problem тело ответа об ошибке, RFC 7807.
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	Status    int    `json:"status"`
+}
@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/pkg/amount"
+)
+
+// Amount сумма баллов в API v2 в сотых долях балла. В JSON передаётся строкой с десятичной дробью
+// ("500.50"), чтобы ни сервер, ни клиенты не теряли точность при разборе чисел с плавающей точкой.
+type Amount int64
+
+var errInvalidAmount = amount.ErrInvalid
+
+// newAmount округляет сумму сервисного слоя до сотых.
+func newAmount(v float64) Amount {
+	return Amount(amount.FromFloat(v))
+}
+
+// Float64 возвращает сумму в баллах для сервисного слоя.
+func (a Amount) Float64() float64 {
+	return amount.ToFloat(int64(a))
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(amount.Format(int64(a)))), nil
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return errInvalidAmount
+	}
+
+	v, err := amount.Parse(s)
+	if err != nil {
+		return fmt.Errorf("error of parse amount:%w", err)
+	}
+
+	*a = Amount(v)
+	return nil
+}
+
+type TokenV2 struct {
+	Token string `json:"token"`
+}
+
+type OrderInV2 struct {
+	Number string `json:"number"`
+}
+
+//nolint:govet //incorrectly detects alignment
+type OrderV2 struct {
+	UploadedAt time.Time `json:"uploaded_at"`
+	Accrual    *Amount   `json:"accrual,omitempty"`
+	Number     string    `json:"number"`
+	Status     string    `json:"status"`
+}
+
+type OrderDetailsV2 struct {
+	OrderV2
+	Polls []OrderPollV2 `json:"polls"`
+}
+
+//nolint:govet //incorrectly detects alignment
+type OrderPollV2 struct {
+	PolledAt time.Time `json:"polled_at"`
+	Accrual  *Amount   `json:"accrual,omitempty"`
+	Status   string    `json:"status"`
+}
+
+type BalanceV2 struct {
+	Current   Amount `json:"current"`
+	Withdrawn Amount `json:"withdrawn"`
+}
+
+type WithdrawInV2 struct {
+	Order string `json:"order"`
+	Sum   Amount `json:"sum"`
+}
+
+type WithdrawalV2 struct {
+	ProcessedAt time.Time `json:"processed_at"`
+	Order       string    `json:"order"`
+	Sum         Amount    `json:"sum"`
+}
+
+// ListV2 страница списка в API v2. NextCursor передаётся в параметре after для получения следующей страницы
+// и отсутствует на последней странице.
+type ListV2[T any] struct {
+	NextCursor string `json:"next_cursor,omitempty"`
+	Items      []T    `json:"items"`
+}
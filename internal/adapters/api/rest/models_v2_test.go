@@ -0,0 +1,50 @@
+package rest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmount(t *testing.T) {
+	data, err := json.Marshal(BalanceV2{Current: newAmount(500.5), Withdrawn: newAmount(42)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"current":"500.50","withdrawn":"42.00"}`, string(data))
+	data, err = json.Marshal(Amount(-5))
+	require.NoError(t, err)
+	assert.Equal(t, `"-0.05"`, string(data))
+	assert.Equal(t, Amount(1), newAmount(0.005+0.004))
+	assert.InDelta(t, 751.25, Amount(75125).Float64(), 1e-9)
+
+	tests := []struct {
+		name    string
+		in      string
+		want    Amount
+		wantErr bool
+	}{
+		{name: "decimal string", in: `{"order":"1","sum":"751.25"}`, want: 75125},
+		{name: "one fraction digit", in: `{"order":"1","sum":"0.1"}`, want: 10},
+		{name: "integer string", in: `{"order":"1","sum":"100"}`, want: 10000},
+		{name: "negative", in: `{"order":"1","sum":"-100"}`, wantErr: true},
+		{name: "too precise", in: `{"order":"1","sum":"0.001"}`, wantErr: true},
+		{name: "exponent", in: `{"order":"1","sum":"1e3"}`, wantErr: true},
+		{name: "overflow", in: `{"order":"1","sum":"92233720368547758.08"}`, wantErr: true},
+		{name: "number", in: `{"order":"1","sum":751.25}`, wantErr: true},
+		{name: "not a number", in: `{"order":"1","sum":"abc"}`, wantErr: true},
+		{name: "infinity", in: `{"order":"1","sum":"Inf"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w WithdrawInV2
+			err := json.Unmarshal([]byte(tt.in), &w)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, w.Sum)
+		})
+	}
+}
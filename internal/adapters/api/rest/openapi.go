@@ -0,0 +1,143 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+// openAPISpec спецификация REST API. Должна описывать все маршруты BuildRouter, это проверяется тестом.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// maxValidatedResponse ограничивает размер ответа, проверяемого на соответствие спецификации.
+// Ответы больше (например, поток событий) не проверяются.
+const maxValidatedResponse = 1 << 20
+
+func loadOpenAPI(ctx context.Context) (*openapi3.T, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(openAPISpec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load openapi spec:%w", err)
+	}
+
+	err = doc.Validate(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid openapi spec:%w", err)
+	}
+
+	return doc, nil
+}
+
+func getOpenAPI(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	_, err := rw.Write(openAPISpec)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write openapi spec")
+		return
+	}
+}
+
+// validateOpenAPI проверяет запросы и ответы на соответствие спецификации. Используется только в режиме
+// разработки: запрос, не соответствующий спецификации, отклоняется с кодом 400, несоответствие ответа
+// только логируется. Аутентификация здесь не проверяется, этим занимается authenticate.
+func validateOpenAPI(doc *openapi3.T) (func(next http.Handler) http.Handler, error) {
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create openapi router:%w", err)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			route, pathParams, err := router.FindRoute(r)
+			if err != nil {
+				if !errors.Is(err, routers.ErrPathNotFound) && !errors.Is(err, routers.ErrMethodNotAllowed) {
+					log.Ctx(r.Context()).Error().Err(err).Msg("error of find openapi route")
+				}
+				next.ServeHTTP(rw, r)
+				return
+			}
+
+			input := &openapi3filter.RequestValidationInput{
+				Request:    r,
+				PathParams: pathParams,
+				Route:      route,
+				Options: &openapi3filter.Options{
+					AuthenticationFunc:    openapi3filter.NoopAuthenticationFunc,
+					IncludeResponseStatus: true,
+				},
+			}
+
+			err = openapi3filter.ValidateRequest(r.Context(), input)
+			if err != nil {
+				var mbe *http.MaxBytesError
+				if errors.As(err, &mbe) {
+					writeProblem(rw, r, http.StatusRequestEntityTooLarge, codeBodyTooLarge)
+					return
+				}
+
+				log.Ctx(r.Context()).Warn().Err(err).Msg("request does not match openapi spec")
+				writeProblemDetail(rw, r, http.StatusBadRequest, codeInvalidRequest, err.Error())
+				return
+			}
+
+			ww := middleware.NewWrapResponseWriter(rw, r.ProtoMajor)
+			body := &limitedBuffer{limit: maxValidatedResponse}
+			ww.Tee(body)
+
+			next.ServeHTTP(ww, r)
+
+			if body.truncated || strings.HasPrefix(ww.Header().Get("Content-Type"), "text/event-stream") {
+				return
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			err = openapi3filter.ValidateResponse(r.Context(), &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: input,
+				Status:                 status,
+				Header:                 ww.Header(),
+				Body:                   io.NopCloser(bytes.NewReader(body.Bytes())),
+				Options:                input.Options,
+			})
+			if err != nil {
+				log.Ctx(r.Context()).Warn().Err(err).Str("method", r.Method).Str("path", r.URL.Path).
+					Msg("response does not match openapi spec")
+			}
+		})
+	}, nil
+}
+
+// limitedBuffer запоминает не более limit байт, остальное отбрасывает.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.truncated || b.Len()+len(p) > b.limit {
+		b.truncated = true
+		b.Reset()
+		return len(p), nil
+	}
+
+	//nolint:wrapcheck //bytes.Buffer never returns error
+	return b.Buffer.Write(p)
+}
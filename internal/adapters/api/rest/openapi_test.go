@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/k0st1a/gophermart/internal/pkg/sso"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider включает маршруты входа через OpenID Connect, сам провайдер в тестах не вызывается.
+type stubProvider struct{}
+
+func (stubProvider) AuthCodeURL(_, _ string) string {
+	return ""
+}
+
+func (stubProvider) Exchange(_ context.Context, _, _ string) (*sso.Identity, error) {
+	return nil, nil
+}
+
+func newTestRouter(t *testing.T, devMode bool) *chi.Mux {
+	t.Helper()
+
+	h := NewHandler(nil, nil, nil, nil, nil, nil, stubProvider{}, nil, nil, nil, nil)
+	r, err := BuildRouter(h, nil, nil, nil, RouterOptions{DevMode: devMode})
+	require.NoError(t, err)
+
+	return r
+}
+
+// TestOpenAPIMatchesRouter проверяет, что спецификация описывает ровно те маршруты, которые обслуживает сервер.
+func TestOpenAPIMatchesRouter(t *testing.T) {
+	doc, err := loadOpenAPI(context.Background())
+	require.NoError(t, err)
+
+	var specRoutes []string
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			specRoutes = append(specRoutes, method+" "+path)
+		}
+	}
+
+	var routerRoutes []string
+	err = chi.Walk(newTestRouter(t, false), func(method, route string, _ http.Handler,
+		_ ...func(http.Handler) http.Handler) error {
+		// Маршрут "/" подмаршрутизатора chi отдаёт с завершающим слешем, хотя обслуживает и путь без него.
+		if route != "/" {
+			route = strings.TrimSuffix(route, "/")
+		}
+		routerRoutes = append(routerRoutes, method+" "+route)
+		return nil
+	})
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, specRoutes, routerRoutes)
+}
+
+func TestValidateOpenAPI(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{
+			name:   "Missing password",
+			method: http.MethodPost,
+			path:   "/api/user/register",
+			body:   `{"login":"user"}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Limit out of range",
+			method: http.MethodGet,
+			path:   "/api/user/orders?limit=0",
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "Spec",
+			method: http.MethodGet,
+			path:   "/api/openapi.json",
+			status: http.StatusOK,
+		},
+		{
+			name:   "Unknown route",
+			method: http.MethodGet,
+			path:   "/api/unknown",
+			status: http.StatusNotFound,
+		},
+	}
+
+	r := newTestRouter(t, true)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			req.Header.Set("Content-Type", "application/json")
+			rw := httptest.NewRecorder()
+
+			r.ServeHTTP(rw, req)
+
+			assert.Equal(t, test.status, rw.Code)
+			if test.status >= http.StatusBadRequest {
+				assert.Equal(t, problemContentType, rw.Header().Get("Content-Type"))
+
+				var p Problem
+				require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &p))
+				assert.Equal(t, test.status, p.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package rest
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/pkg/order"
+	"github.com/k0st1a/gophermart/internal/ports"
+)
+
+var errInvalidListParam = errors.New("invalid list parameter")
+
+// parseListFilter разбирает параметры запроса списка:
+//   - limit - размер страницы, по умолчанию defaultLimit, не более ports.MaxListLimit. defaultLimit 0 - без
+//     ограничения, пока не задан курсор after, тогда ports.DefaultListLimit;
+//   - after - курсор из ссылки на следующую страницу;
+//   - status - статус заказа, можно указать несколько раз или через запятую (только если withStatus);
+//   - from, to - полуинтервал [from, to) по времени в формате RFC3339;
+//   - sort - asc (по умолчанию) или desc.
+func parseListFilter(r *http.Request, withStatus bool, defaultLimit int) (ports.ListFilter, error) {
+	q := r.URL.Query()
+	f := ports.ListFilter{
+		Limit: defaultLimit,
+	}
+
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > ports.MaxListLimit {
+			return f, fmt.Errorf("%w: limit must be in range 1..%d", errInvalidListParam, ports.MaxListLimit)
+		}
+		f.Limit = limit
+	}
+
+	if v := q.Get("after"); v != "" {
+		after, err := decodeCursor(v)
+		if err != nil {
+			return f, fmt.Errorf("%w: after", errInvalidListParam)
+		}
+		f.After = after
+
+		if f.Limit == 0 {
+			f.Limit = ports.DefaultListLimit
+		}
+	}
+
+	for _, v := range q["status"] {
+		if !withStatus {
+			return f, fmt.Errorf("%w: status is not supported", errInvalidListParam)
+		}
+
+		for _, status := range strings.Split(v, ",") {
+			status = strings.ToUpper(strings.TrimSpace(status))
+			if !slices.Contains(order.Statuses, status) {
+				return f, fmt.Errorf("%w: unknown status %q", errInvalidListParam, status)
+			}
+			f.Statuses = append(f.Statuses, status)
+		}
+	}
+
+	var err error
+	f.From, err = parseTime(q.Get("from"))
+	if err != nil {
+		return f, fmt.Errorf("%w: from", errInvalidListParam)
+	}
+
+	f.To, err = parseTime(q.Get("to"))
+	if err != nil {
+		return f, fmt.Errorf("%w: to", errInvalidListParam)
+	}
+
+	switch q.Get("sort") {
+	case "", "asc":
+	case "desc":
+		f.Desc = true
+	default:
+		return f, fmt.Errorf("%w: sort must be asc or desc", errInvalidListParam)
+	}
+
+	return f, nil
+}
+
+func parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return t, fmt.Errorf("failed to parse time:%w", err)
+	}
+
+	return t, nil
+}
+
+// setNextLink добавляет заголовок Link на следующую страницу, если текущая страница заполнена полностью.
+func setNextLink(rw http.ResponseWriter, r *http.Request, f ports.ListFilter, count int, lastID int64) {
+	if f.Limit <= 0 || count < f.Limit {
+		return
+	}
+
+	q := r.URL.Query()
+	q.Set("after", encodeCursor(lastID))
+	q.Set("limit", strconv.Itoa(f.Limit))
+
+	u := *r.URL
+	u.RawQuery = q.Encode()
+	rw.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI()))
+}
+
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode cursor:%w", err)
+	}
+
+	id, err := strconv.ParseInt(string(b), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse cursor:%w", err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,131 @@
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListFilter(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 12, 30, 0, 0, time.FixedZone("", 3*60*60))
+
+	tests := []struct {
+		name         string
+		query        string
+		withStatus   bool
+		defaultLimit int
+		want         ports.ListFilter
+		wantErr      bool
+	}{
+		{
+			name:  "v1 without limit and cursor is unlimited",
+			query: "",
+			want:  ports.ListFilter{},
+		},
+		{
+			name:  "v1 cursor without limit uses default limit",
+			query: "after=" + encodeCursor(42),
+			want:  ports.ListFilter{After: 42, Limit: ports.DefaultListLimit},
+		},
+		{
+			name:         "v2 default limit",
+			query:        "",
+			defaultLimit: ports.DefaultListLimit,
+			want:         ports.ListFilter{Limit: ports.DefaultListLimit},
+		},
+		{
+			name: "All parameters",
+			query: "limit=10&after=" + encodeCursor(7) + "&status=new,Processing&status=INVALID" +
+				"&from=2024-01-01T00:00:00Z&to=2024-02-01T12:30:00%2B03:00&sort=desc",
+			withStatus:   true,
+			defaultLimit: ports.DefaultListLimit,
+			want: ports.ListFilter{
+				Limit:    10,
+				After:    7,
+				Statuses: []string{"NEW", "PROCESSING", "INVALID"},
+				From:     from,
+				To:       to,
+				Desc:     true,
+			},
+		},
+		{
+			name:  "Max limit",
+			query: "limit=1000",
+			want:  ports.ListFilter{Limit: ports.MaxListLimit},
+		},
+		{name: "Zero limit", query: "limit=0", wantErr: true},
+		{name: "Limit above max", query: "limit=1001", wantErr: true},
+		{name: "Limit is not a number", query: "limit=ten", wantErr: true},
+		{name: "Cursor is not base64", query: "after=***", wantErr: true},
+		{name: "Cursor is not a number", query: "after=" + "YWJj", wantErr: true},
+		{name: "Unknown status", query: "status=DONE", withStatus: true, wantErr: true},
+		{name: "Status is not supported", query: "status=NEW", wantErr: true},
+		{name: "Invalid from", query: "from=2024-01-01", wantErr: true},
+		{name: "Invalid to", query: "to=yesterday", wantErr: true},
+		{name: "Invalid sort", query: "sort=random", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/api/user/orders?"+test.query, http.NoBody)
+
+			f, err := parseListFilter(r, test.withStatus, test.defaultLimit)
+			if test.wantErr {
+				assert.ErrorIs(t, err, errInvalidListParam)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, test.want.From.Equal(f.From), "from:%v", f.From)
+			assert.True(t, test.want.To.Equal(f.To), "to:%v", f.To)
+			test.want.From, test.want.To = f.From, f.To
+			assert.Equal(t, test.want, f)
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	for _, id := range []int64{1, 42, 1<<63 - 1} {
+		cursor := encodeCursor(id)
+		assert.NotContains(t, cursor, "=")
+
+		got, err := decodeCursor(cursor)
+		require.NoError(t, err)
+		assert.Equal(t, id, got)
+	}
+}
+
+func TestSetNextLink(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter ports.ListFilter
+		count  int
+		want   string
+	}{
+		{
+			name:   "Full page",
+			filter: ports.ListFilter{Limit: 2},
+			count:  2,
+			want:   `</api/user/orders?after=` + encodeCursor(9) + `&limit=2&status=NEW>; rel="next"`,
+		},
+		{name: "Last page", filter: ports.ListFilter{Limit: 2}, count: 1},
+		{name: "Unlimited", filter: ports.ListFilter{}, count: 5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rw := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/api/user/orders?status=NEW", http.NoBody)
+
+			setNextLink(rw, r, test.filter, test.count, 9)
+
+			assert.Equal(t, test.want, rw.Header().Get("Link"))
+		})
+	}
+}
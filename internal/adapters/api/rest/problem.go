@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+const problemContentType = "application/problem+json"
+
+// Коды ошибок API (поле code ответа). Значения стабильны: клиенты могут на них полагаться,
+// в отличие от текста detail.
+const (
+	codeInvalidRequest      = "invalid_request"
+	codeUnauthorized        = "unauthorized"
+	codeForbidden           = "forbidden"
+	codeNotFound            = "not_found"
+	codeMethodNotAllowed    = "method_not_allowed"
+	codeInternal            = "internal_error"
+	codeLoginTaken          = "login_taken"
+	codeInvalidCredentials  = "invalid_credentials"
+	codeInvalidOrderNumber  = "invalid_order_number"
+	codeOrderConflict       = "order_uploaded_by_another_user"
+	codeInsufficientFunds   = "insufficient_funds"
+	codeNegativeBalance     = "negative_balance"
+	codeInvalidListParam    = "invalid_list_parameter"
+	codeValidationFailed    = "validation_failed"
+	codeOIDCFailed          = "oidc_failed"
+	codeBodyTooLarge        = "request_body_too_large"
+	codeUnsupportedEncoding = "unsupported_content_encoding"
+	codeAccountLocked       = "account_locked"
+)
+
+// writeProblem пишет ответ об ошибке в формате RFC 7807 (application/problem+json).
+func writeProblem(rw http.ResponseWriter, r *http.Request, status int, code string) {
+	writeProblemDetail(rw, r, status, code, "")
+}
+
+// writeProblemDetail пишет ответ об ошибке с пояснением detail. В detail допустим только текст, предназначенный
+// клиенту (например, ошибка валидации), но не внутренние ошибки сервера.
+func writeProblemDetail(rw http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	data, err := json.Marshal(&Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: middleware.GetReqID(r.Context()),
+	})
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of serialize problem")
+		rw.WriteHeader(status)
+		return
+	}
+
+	rw.Header().Set("Content-Type", problemContentType)
+	rw.Header().Set("X-Content-Type-Options", "nosniff")
+	rw.WriteHeader(status)
+	_, err = rw.Write(data)
+	if err != nil {
+		log.Ctx(r.Context()).Error().Err(err).Msg("error of write problem")
+		return
+	}
+}
+
+func notFound(rw http.ResponseWriter, r *http.Request) {
+	writeProblem(rw, r, http.StatusNotFound, codeNotFound)
+}
+
+func methodNotAllowed(rw http.ResponseWriter, r *http.Request) {
+	writeProblem(rw, r, http.StatusMethodNotAllowed, codeMethodNotAllowed)
+}
+
+// recoverer перехватывает панику обработчика и отвечает ошибкой 500.
+func recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+
+			//nolint:goerr113,errorlint //http.ErrAbortHandler must be compared directly
+			if rvr == http.ErrAbortHandler {
+				panic(rvr)
+			}
+
+			log.Ctx(r.Context()).Error().
+				Interface("panic", rvr).
+				Bytes("stack", debug.Stack()).
+				Msg("panic in handler")
+			writeProblem(rw, r, http.StatusInternalServerError, codeInternal)
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
@@ -1,32 +1,175 @@
 package rest
 
 import (
+	"context"
+	"net/http"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/k0st1a/gophermart/internal/pkg/apikey"
 	"github.com/k0st1a/gophermart/internal/pkg/auth"
+	"github.com/k0st1a/gophermart/internal/pkg/health"
+	"github.com/k0st1a/gophermart/internal/pkg/session"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func BuildRouter(h *handler, a auth.UserAuthentication) *chi.Mux {
+// RouterOptions настройки маршрутизатора REST API.
+type RouterOptions struct {
+	// MaxBodySize максимальный размер тела запроса после распаковки, 0 - DefaultMaxBodySize.
+	MaxBodySize int64
+	// Health проверки готовности для /readyz, nil - сервис всегда готов.
+	Health *health.Health
+	// DevMode включает проверку запросов и ответов на соответствие спецификации OpenAPI.
+	DevMode bool
+}
+
+// BuildMetricsRouter создаёт маршрутизатор внутреннего адреса метрик: только /metrics. Метрики не отдаются
+// на адресе API, так как раскрывают состояние пула соединений, очереди опроса и трафик по маршрутам.
+// nil metrics - метрики реестра Prometheus по умолчанию.
+func BuildMetricsRouter(metrics http.Handler) *chi.Mux {
+	if metrics == nil {
+		metrics = promhttp.Handler()
+	}
+
+	r := chi.NewRouter()
+
+	r.Use(recoverer)
+
+	r.NotFound(notFound)
+	r.MethodNotAllowed(methodNotAllowed)
+
+	r.Method(http.MethodGet, `/metrics`, metrics)
+
+	return r
+}
+
+// BuildProbeRouter создаёт маршрутизатор процесса без REST API (gophermart worker): только /healthz и /readyz.
+// Из opts используется Health.
+func BuildProbeRouter(opts RouterOptions) *chi.Mux {
 	r := chi.NewRouter()
 
 	r.Use(middleware.RequestID)
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
+	r.Use(logRequests)
+	r.Use(instrument)
+	r.Use(recoverer)
+
+	r.NotFound(notFound)
+	r.MethodNotAllowed(methodNotAllowed)
+
+	r.Get(`/healthz`, liveness)
+	r.Get(`/readyz`, readiness(opts.Health))
+
+	return r
+}
+
+// BuildRouter создаёт маршрутизатор REST API. Ответы сжимаются (gzip, deflate) по заголовку Accept-Encoding,
+// тело запроса распаковывается и ограничивается по размеру, см. limitBody.
+func BuildRouter(h *handler, a auth.UserAuthentication, k apikey.Managment, s session.Managment,
+	opts RouterOptions) (*chi.Mux, error) {
+	if opts.MaxBodySize == 0 {
+		opts.MaxBodySize = DefaultMaxBodySize
+	}
+	r := chi.NewRouter()
+
+	r.Use(middleware.RequestID)
+	r.Use(logRequests)
+	r.Use(traceRequests)
+	r.Use(instrument)
+	r.Use(recoverer)
+	r.Use(middleware.Compress(compressLevel, compressibleTypes...))
+	r.Use(limitBody(opts.MaxBodySize))
+
+	if opts.DevMode {
+		doc, err := loadOpenAPI(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		validate, err := validateOpenAPI(doc)
+		if err != nil {
+			return nil, err
+		}
+		r.Use(validate)
+	}
+
+	r.NotFound(notFound)
+	r.MethodNotAllowed(methodNotAllowed)
+
+	r.Get(`/api/openapi.json`, getOpenAPI)
+	r.Get(`/healthz`, liveness)
+	r.Get(`/readyz`, readiness(opts.Health))
 
 	r.Route(`/api/user`, func(r chi.Router) {
+		// v1 подключает deprecated раньше authenticate и requireScope, чтобы заголовки устаревания были
+		// и в ответах 401 и 403. Пустой successor - у маршрута нет замены в v2.
+		v1 := func(successor string, middlewares ...func(http.Handler) http.Handler) chi.Router {
+			return r.With(deprecated(successor)).With(middlewares...)
+		}
+		authenticated := authenticate(a, h.user, k, s)
+
+		v1(`/api/v2/user/register`).Post(`/register`, h.register)
+		v1(`/api/v2/user/login`).Post(`/login`, h.login)
+		if h.sso != nil {
+			v1(``).Get(`/oidc/login`, h.oidcLogin)
+			v1(``).Get(`/oidc/callback`, h.oidcCallback)
+		}
+
+		v1(`/api/v2/user/orders`, authenticated, requireScope(apikey.ScopeOrdersWrite)).Post(`/orders`, h.createOrder)
+		v1(``, authenticated, requireScope(apikey.ScopeOrdersWrite)).Post(`/orders/batch`, h.createOrderBatch)
+		v1(`/api/v2/user/orders`, authenticated, requireScope(apikey.ScopeOrdersRead)).Get(`/orders`, h.getOrders)
+		v1(``, authenticated, requireScope(apikey.ScopeOrdersRead)).Get(`/orders/events`, h.getOrderEvents)
+		v1(`/api/v2/user/orders/{number}`, authenticated, requireScope(apikey.ScopeOrdersRead)).
+			Get(`/orders/{number}`, h.getOrder)
+		v1(`/api/v2/user/balance`, authenticated, requireScope(apikey.ScopeBalanceRead)).Get(`/balance`, h.getBalance)
+		v1(`/api/v2/user/withdrawals`, authenticated, requireScope(apikey.ScopeWithdrawalsWrite)).
+			Post(`/balance/withdraw`, h.createWithdraw)
+		v1(`/api/v2/user/withdrawals`, authenticated, requireScope(apikey.ScopeWithdrawalsRead)).
+			Get(`/withdrawals`, h.getWithdrawals)
+		v1(``, authenticated, requireScope(apikey.ScopeWebhooksWrite)).Post(`/webhooks`, h.createWebhook)
+		v1(``, authenticated, requireScope(apikey.ScopeWebhooksRead)).Get(`/webhooks`, h.getWebhooks)
+		v1(``, authenticated, requireScope(apikey.ScopeWebhooksWrite)).Delete(`/webhooks/{id}`, h.deleteWebhook)
+		v1(``, authenticated, requireScope(apikey.ScopeWebhooksRead)).
+			Get(`/webhooks/{id}/deliveries`, h.getWebhookDeliveries)
+		v1(``, authenticated, userTokenOnly).Get(`/export`, h.exportUser)
+		v1(``, authenticated, userTokenOnly).Delete(`/`, h.deleteUser)
+		v1(``, authenticated, userTokenOnly).Get(`/sessions`, h.getSessions)
+		v1(``, authenticated, userTokenOnly).Delete(`/sessions/{id}`, h.terminateSession)
+	})
+
+	r.Route(`/api/v2/user`, func(r chi.Router) {
 		r.Group(func(r chi.Router) {
-			r.Post(`/register`, h.register)
-			r.Post(`/login`, h.login)
+			r.Post(`/register`, h.registerV2)
+			r.Post(`/login`, h.loginV2)
 		})
 		r.Group(func(r chi.Router) {
-			r.Use(authenticate(a))
-			r.Post(`/orders`, h.createOrder)
-			r.Get(`/orders`, h.getOrders)
-			r.Get(`/balance`, h.getBalance)
-			r.Post(`/balance/withdraw`, h.createWithdraw)
-			r.Get(`/withdrawals`, h.getWithdrawals)
+			r.Use(authenticate(a, h.user, k, s))
+			r.With(requireScope(apikey.ScopeOrdersWrite)).Post(`/orders`, h.createOrderV2)
+			r.With(requireScope(apikey.ScopeOrdersRead)).Get(`/orders`, h.getOrdersV2)
+			r.With(requireScope(apikey.ScopeOrdersRead)).Get(`/orders/{number}`, h.getOrderV2)
+			r.With(requireScope(apikey.ScopeBalanceRead)).Get(`/balance`, h.getBalanceV2)
+			r.With(requireScope(apikey.ScopeWithdrawalsWrite)).Post(`/withdrawals`, h.createWithdrawalV2)
+			r.With(requireScope(apikey.ScopeWithdrawalsRead)).Get(`/withdrawals`, h.getWithdrawalsV2)
 		})
 	})
 
-	return r
+	r.Route(`/api/admin`, func(r chi.Router) {
+		r.Use(authenticate(a, h.user, k, s))
+		r.Route(`/users/{login}`, func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(authorize(auth.RoleSupport, auth.RoleAdmin))
+				r.Get(`/`, h.getAdminUser)
+				r.Get(`/orders`, h.getAdminUserOrders)
+				r.Get(`/withdrawals`, h.getAdminUserWithdrawals)
+				r.Get(`/apikeys`, h.getAPIKeys)
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(authorize(auth.RoleAdmin))
+				r.Post(`/balance`, h.adjustBalance)
+				r.Post(`/apikeys`, h.createAPIKey)
+				r.Delete(`/apikeys/{id}`, h.revokeAPIKey)
+			})
+		})
+	})
+
+	return r, nil
 }
@@ -0,0 +1,103 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/k0st1a/gophermart/internal/pkg/apikey"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubKeys аутентифицирует любой API ключ с правом только на чтение заказов.
+type stubKeys struct {
+	apikey.Managment
+}
+
+func (stubKeys) Authenticate(_ context.Context, _ string) (*apikey.Key, error) {
+	return &apikey.Key{ID: 1, UserID: 1, Scopes: []string{apikey.ScopeOrdersRead}}, nil
+}
+
+func TestDeprecatedV1(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		apiKey     bool
+		status     int
+		deprecated bool
+		link       string
+	}{
+		{
+			name:       "Unauthorized v1 order",
+			method:     http.MethodGet,
+			path:       "/api/user/orders/12345678903",
+			status:     http.StatusUnauthorized,
+			deprecated: true,
+			link:       `</api/v2/user/orders/12345678903>; rel="successor-version"`,
+		},
+		{
+			name:       "Unauthorized v1 withdraw",
+			method:     http.MethodPost,
+			path:       "/api/user/balance/withdraw",
+			status:     http.StatusUnauthorized,
+			deprecated: true,
+			link:       `</api/v2/user/withdrawals>; rel="successor-version"`,
+		},
+		{
+			name:       "Forbidden v1 order upload",
+			method:     http.MethodPost,
+			path:       "/api/user/orders",
+			apiKey:     true,
+			status:     http.StatusForbidden,
+			deprecated: true,
+			link:       `</api/v2/user/orders>; rel="successor-version"`,
+		},
+		{
+			name:       "Unauthorized v1 batch without successor",
+			method:     http.MethodPost,
+			path:       "/api/user/orders/batch",
+			status:     http.StatusUnauthorized,
+			deprecated: true,
+		},
+		{
+			name:       "Unauthorized v1 events without successor",
+			method:     http.MethodGet,
+			path:       "/api/user/orders/events",
+			status:     http.StatusUnauthorized,
+			deprecated: true,
+		},
+		{
+			name:   "Unauthorized v2 order",
+			method: http.MethodGet,
+			path:   "/api/v2/user/orders/12345678903",
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	h := NewHandler(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	r, err := BuildRouter(h, nil, stubKeys{}, nil, RouterOptions{})
+	require.NoError(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, http.NoBody)
+			if test.apiKey {
+				req.Header.Set("X-API-Key", "gm_test")
+			}
+			rw := httptest.NewRecorder()
+
+			r.ServeHTTP(rw, req)
+
+			assert.Equal(t, test.status, rw.Code)
+			if test.deprecated {
+				assert.Equal(t, "true", rw.Header().Get("Deprecation"))
+			} else {
+				assert.Empty(t, rw.Header().Get("Deprecation"))
+			}
+			assert.Equal(t, test.link, rw.Header().Get("Link"))
+		})
+	}
+}
@@ -5,28 +5,51 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
+type ctxShutdown struct{}
+
+// ServerOptions настройки HTTP сервера.
+type ServerOptions struct {
+	// ReadHeaderTimeout время на чтение заголовков запроса.
+	ReadHeaderTimeout time.Duration
+	// IdleTimeout время ожидания следующего запроса в keep-alive соединении.
+	IdleTimeout time.Duration
+}
+
 type server struct {
 	server *http.Server
+	cancel context.CancelFunc
 }
 
-func New(ctx context.Context, address string, handler http.Handler) *server {
+// New создаёт HTTP сервер. Контексты запросов наследуют значения ctx, но не его отмену: при остановке
+// сервера обрабатываемые запросы дорабатывают, см. Shutdown.
+func New(ctx context.Context, address string, handler http.Handler, opts ServerOptions) *server {
+	baseCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	closing := make(chan struct{})
+	baseCtx = context.WithValue(baseCtx, ctxShutdown{}, (<-chan struct{})(closing))
+
 	s := &http.Server{
-		BaseContext: func(_ net.Listener) context.Context { return ctx },
-		Addr:        address,
-		Handler:     handler,
+		BaseContext:       func(_ net.Listener) context.Context { return baseCtx },
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: opts.ReadHeaderTimeout,
+		IdleTimeout:       opts.IdleTimeout,
 	}
+	s.RegisterOnShutdown(func() { close(closing) })
 
 	return &server{
 		server: s,
+		cancel: cancel,
 	}
 }
 
 func (s *server) Run() error {
-	log.Printf("Run api")
+	log.Info().Str("address", s.server.Addr).Msg("run api")
 
 	err := s.server.ListenAndServe()
 	if err != nil {
@@ -36,7 +59,25 @@ func (s *server) Run() error {
 	return nil
 }
 
+// Shutdown перестаёт принимать новые соединения и дожидается завершения обрабатываемых запросов, но не дольше,
+// чем до отмены ctx. После этого контексты оставшихся запросов отменяются, а соединения закрываются.
+// Потоки событий (text/event-stream) завершаются сразу, см. shuttingDown.
 func (s *server) Shutdown(ctx context.Context) error {
-	//nolint:wrapcheck //no need here
-	return s.server.Shutdown(ctx)
+	err := s.server.Shutdown(ctx)
+	if err != nil {
+		s.cancel()
+		_ = s.server.Close()
+		return fmt.Errorf("shutdown error:%w", err)
+	}
+
+	s.cancel()
+	return nil
+}
+
+// shuttingDown возвращает канал, который закрывается в начале остановки сервера. Используется длительными
+// запросами, которые иначе задержали бы остановку до истечения её таймаута. Вне сервера (в тестах)
+// возвращает nil канал.
+func shuttingDown(ctx context.Context) <-chan struct{} {
+	ch, _ := ctx.Value(ctxShutdown{}).(<-chan struct{})
+	return ch
 }
@@ -0,0 +1,317 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strconv"
+
+	"github.com/ShiraazMoollatjie/goluhn"
+	pb "github.com/k0st1a/gophermart/internal/adapters/api/rpc/proto"
+	"github.com/k0st1a/gophermart/internal/pkg/amount"
+	"github.com/k0st1a/gophermart/internal/pkg/auth"
+	"github.com/k0st1a/gophermart/internal/pkg/order"
+	"github.com/k0st1a/gophermart/internal/pkg/session"
+	"github.com/k0st1a/gophermart/internal/pkg/user"
+	"github.com/k0st1a/gophermart/internal/pkg/withdraw"
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var errInternal = status.Error(codes.Internal, "internal error")
+
+type handler struct {
+	pb.UnimplementedGophermartServer
+	auth     auth.UserAuthentication
+	user     user.Managment
+	order    order.Managment
+	withdraw withdraw.Managment
+	issuer   session.Issuer
+}
+
+// NewHandler создаёт реализацию gRPC сервиса Gophermart поверх тех же сервисов, что и REST API.
+func NewHandler(a auth.UserAuthentication, u user.Managment, o order.Managment, w withdraw.Managment,
+	s session.Managment) *handler {
+	return &handler{
+		auth:     a,
+		user:     u,
+		order:    o,
+		withdraw: w,
+		issuer:   session.NewIssuer(a, u, s),
+	}
+}
+
+func (h *handler) Register(ctx context.Context, in *pb.Credentials) (*pb.Token, error) {
+	passwordHash, err := h.auth.GeneratePasswordHash(in.GetPassword())
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("error of generate password hash")
+		return nil, errInternal
+	}
+
+	id, err := h.user.Create(ctx, in.GetLogin(), passwordHash)
+	if err != nil {
+		if errors.Is(err, user.ErrLoginAlreadyBusy) {
+			return nil, status.Error(codes.AlreadyExists, "login is already taken")
+		}
+
+		log.Ctx(ctx).Error().Err(err).Msg("error of create user")
+		return nil, errInternal
+	}
+
+	t, err := h.issuer.Issue(ctx, id, clientOf(ctx))
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("error of issue token")
+		return nil, errInternal
+	}
+
+	return &pb.Token{Token: t}, nil
+}
+
+func (h *handler) Login(ctx context.Context, in *pb.Credentials) (*pb.Token, error) {
+	t, err := h.issuer.Login(ctx, in.GetLogin(), in.GetPassword(), clientOf(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, session.ErrInvalidCredentials):
+			return nil, status.Error(codes.Unauthenticated, "invalid login or password")
+		case errors.Is(err, user.ErrLocked):
+			return nil, status.Error(codes.PermissionDenied, "account is locked")
+		default:
+			log.Ctx(ctx).Error().Err(err).Msg("error of login")
+			return nil, errInternal
+		}
+	}
+
+	return &pb.Token{Token: t}, nil
+}
+
+// clientOf возвращает клиента вызова для новой сессии: user-agent из метаданных и адрес из peer.
+func clientOf(ctx context.Context) session.Client {
+	var c session.Client
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("user-agent"); len(v) != 0 {
+			c.UserAgent = v[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		c.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(c.IP); err == nil {
+			c.IP = host
+		}
+	}
+
+	return c
+}
+
+func (h *handler) UploadOrder(ctx context.Context, in *pb.UploadOrderRequest) (*pb.UploadOrderResponse, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	orderID, err := parseOrderNumber(in.GetNumber())
+	if err != nil {
+		return nil, err
+	}
+
+	var alreadyUploaded bool
+	err = h.order.Create(ctx, userID, orderID)
+	if err != nil {
+		switch {
+		case errors.Is(err, order.ErrAlreadyUploadedByThisUser):
+			alreadyUploaded = true
+		case errors.Is(err, order.ErrAlreadyUploadedByAnotherUser):
+			return nil, status.Error(codes.AlreadyExists, "order uploaded by another user")
+		default:
+			log.Ctx(ctx).Error().Err(err).Msg("create order error")
+			return nil, errInternal
+		}
+	}
+
+	d, err := h.order.Get(ctx, userID, orderID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("error of get order")
+		return nil, errInternal
+	}
+
+	return &pb.UploadOrderResponse{
+		Order:           toOrder(&d.Order),
+		AlreadyUploaded: alreadyUploaded,
+	}, nil
+}
+
+func (h *handler) ListOrders(ctx context.Context, in *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	filter, err := toListFilter(in.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range in.GetStatuses() {
+		if !slices.Contains(order.Statuses, s) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown status %q", s)
+		}
+		filter.Statuses = append(filter.Statuses, s)
+	}
+
+	orders, err := h.order.List(ctx, userID, filter)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("error of get orders")
+		return nil, errInternal
+	}
+
+	out := &pb.ListOrdersResponse{Orders: make([]*pb.Order, len(orders))}
+	for i := range orders {
+		out.Orders[i] = toOrder(&orders[i])
+	}
+	if len(orders) == filter.Limit {
+		out.NextAfter = orders[len(orders)-1].Number
+	}
+
+	return out, nil
+}
+
+func (h *handler) GetBalance(ctx context.Context, _ *pb.GetBalanceRequest) (*pb.Balance, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	current, withdrawn, err := h.user.GetBalance(ctx, userID)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("error of get balance")
+		return nil, errInternal
+	}
+
+	return &pb.Balance{
+		Current:   formatAmount(current),
+		Withdrawn: formatAmount(withdrawn),
+	}, nil
+}
+
+func (h *handler) Withdraw(ctx context.Context, in *pb.WithdrawRequest) (*pb.WithdrawResponse, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	orderID, err := parseOrderNumber(in.GetOrder())
+	if err != nil {
+		return nil, err
+	}
+
+	cents, err := amount.Parse(in.GetSum())
+	sum := amount.ToFloat(cents)
+	if err != nil || !withdraw.ValidSum(sum) {
+		return nil, status.Error(codes.InvalidArgument, "sum must be a positive decimal string with at most two decimals")
+	}
+
+	err = h.withdraw.Create(ctx, userID, orderID, sum)
+	if err != nil {
+		if errors.Is(err, withdraw.ErrNotEnoughFunds) {
+			return nil, status.Error(codes.FailedPrecondition, "insufficient funds")
+		}
+
+		log.Ctx(ctx).Error().Err(err).Msg("error of create withdraw")
+		return nil, errInternal
+	}
+
+	return &pb.WithdrawResponse{}, nil
+}
+
+func (h *handler) ListWithdrawals(ctx context.Context, in *pb.ListWithdrawalsRequest) (*pb.ListWithdrawalsResponse,
+	error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	filter, err := toListFilter(in.GetFilter())
+	if err != nil {
+		return nil, err
+	}
+
+	withdrawals, err := h.withdraw.List(ctx, userID, filter)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("error of get withdrawals")
+		return nil, errInternal
+	}
+
+	out := &pb.ListWithdrawalsResponse{Withdrawals: make([]*pb.Withdrawal, len(withdrawals))}
+	for i, w := range withdrawals {
+		out.Withdrawals[i] = &pb.Withdrawal{
+			Order:       strconv.FormatInt(w.Order, 10),
+			Sum:         formatAmount(w.Sum),
+			ProcessedAt: timestamppb.New(w.ProcessedAt),
+		}
+	}
+	if len(withdrawals) == filter.Limit {
+		out.NextAfter = withdrawals[len(withdrawals)-1].ID
+	}
+
+	return out, nil
+}
+
+// parseOrderNumber проверяет номер заказа алгоритмом Луна.
+func parseOrderNumber(number string) (int64, error) {
+	err := goluhn.Validate(number)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "invalid order number")
+	}
+
+	orderID, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		return 0, status.Error(codes.InvalidArgument, "invalid order number")
+	}
+
+	return orderID, nil
+}
+
+func toListFilter(in *pb.ListFilter) (ports.ListFilter, error) {
+	f := ports.ListFilter{
+		Limit: int(in.GetLimit()),
+		After: in.GetAfter(),
+		Desc:  in.GetDesc(),
+	}
+
+	switch {
+	case f.Limit == 0:
+		f.Limit = ports.DefaultListLimit
+	case f.Limit < 0 || f.Limit > ports.MaxListLimit:
+		return f, status.Errorf(codes.InvalidArgument, "limit must be in range 1..%d", ports.MaxListLimit)
+	}
+
+	if in.GetFrom() != nil {
+		f.From = in.GetFrom().AsTime()
+	}
+	if in.GetTo() != nil {
+		f.To = in.GetTo().AsTime()
+	}
+
+	return f, nil
+}
+
+func toOrder(o *order.Order) *pb.Order {
+	out := &pb.Order{
+		Number:     strconv.FormatInt(o.Number, 10),
+		Status:     o.Status,
+		UploadedAt: timestamppb.New(o.UploadedAt),
+	}
+	if o.Status == order.StatusProcessed {
+		out.Accrual = formatAmount(o.Accrual)
+	}
+
+	return out
+}
+
+func formatAmount(v float64) string {
+	return amount.Format(amount.FromFloat(v))
+}
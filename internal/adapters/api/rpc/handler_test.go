@@ -0,0 +1,52 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/k0st1a/gophermart/internal/adapters/api/rpc/proto"
+	"github.com/k0st1a/gophermart/internal/pkg/withdraw"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type stubWithdraw struct {
+	withdraw.Managment
+	sums []float64
+}
+
+func (s *stubWithdraw) Create(_ context.Context, _, _ int64, sum float64) error {
+	s.sums = append(s.sums, sum)
+	return nil
+}
+
+func TestWithdraw(t *testing.T) {
+	tests := []struct {
+		name string
+		sum  string
+		code codes.Code
+		want []float64
+	}{
+		{name: "Two decimals", sum: "751.25", code: codes.OK, want: []float64{751.25}},
+		{name: "Integer", sum: "100", code: codes.OK, want: []float64{100}},
+		{name: "Zero", sum: "0", code: codes.InvalidArgument},
+		{name: "Negative", sum: "-1", code: codes.InvalidArgument},
+		{name: "Too precise", sum: "0.001", code: codes.InvalidArgument},
+		{name: "Exponent", sum: "1e3", code: codes.InvalidArgument},
+		{name: "Hex float", sum: "0x1p-2", code: codes.InvalidArgument},
+		{name: "NaN", sum: "NaN", code: codes.InvalidArgument},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := &stubWithdraw{}
+			h := NewHandler(nil, nil, nil, w, nil)
+			ctx := context.WithValue(context.Background(), ctxUserID{}, int64(1))
+
+			_, err := h.Withdraw(ctx, &pb.WithdrawRequest{Order: "2377225624", Sum: test.sum})
+			assert.Equal(t, test.code, status.Code(err))
+			assert.Equal(t, test.want, w.sums)
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/k0st1a/gophermart/internal/adapters/api/rpc/proto"
+	"github.com/k0st1a/gophermart/internal/pkg/auth"
+	"github.com/k0st1a/gophermart/internal/pkg/logging"
+	"github.com/k0st1a/gophermart/internal/pkg/session"
+	"github.com/k0st1a/gophermart/internal/pkg/user"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type ctxUserID struct{}
+
+// publicMethods методы, доступные без токена.
+var publicMethods = map[string]bool{
+	pb.Gophermart_Register_FullMethodName: true,
+	pb.Gophermart_Login_FullMethodName:    true,
+}
+
+// authenticate проверяет JWT токен из метаданных authorization и сессию, к которой он привязан,
+// так же, как REST API. Идентификатор пользователя передаётся обработчику через контекст.
+func authenticate(a auth.UserAuthentication, users user.Managment,
+	sessions session.Managment) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		var token string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get("authorization"); len(v) != 0 {
+				token = strings.TrimPrefix(v[0], "Bearer ")
+			}
+		}
+		if token == "" {
+			log.Ctx(ctx).Warn().Msg("authorization metadata not set")
+			return nil, status.Error(codes.Unauthenticated, "authorization token required")
+		}
+
+		claims, err := a.GetClaims(token)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("error of get claims")
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		err = sessions.Check(ctx, claims.UserID, claims.SessionID)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("error of check session")
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		err = users.CheckActive(ctx, claims.UserID)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Msg("error of check user active")
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		ctx = logging.SetUserID(ctx, claims.UserID)
+		return handler(context.WithValue(ctx, ctxUserID{}, claims.UserID), req)
+	}
+}
+
+// logCalls добавляет в контекст вызова логгер с идентификатором запроса из метаданных x-request-id, если он
+// передан, и по завершении вызова пишет его в журнал.
+func logCalls(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("x-request-id"); len(v) != 0 {
+			ctx = logging.WithRequestID(ctx, v[0])
+		}
+	}
+	// Собственный логгер вызова, чтобы authenticate добавил к нему идентификатор пользователя.
+	l := log.Ctx(ctx).With().Logger()
+	ctx = l.WithContext(ctx)
+
+	resp, err := handler(ctx, req)
+
+	log.Ctx(ctx).Info().
+		Str("method", info.FullMethod).
+		Str("code", status.Code(err).String()).
+		Dur("duration", time.Since(start)).
+		Msg("call")
+
+	return resp, err
+}
+
+func getUserID(ctx context.Context) (int64, error) {
+	userID, ok := ctx.Value(ctxUserID{}).(int64)
+	if !ok {
+		return 0, fmt.Errorf("user id not found in context")
+	}
+	return userID, nil
+}
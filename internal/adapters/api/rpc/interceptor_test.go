@@ -0,0 +1,53 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	pb "github.com/k0st1a/gophermart/internal/adapters/api/rpc/proto"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestAuthenticate(t *testing.T) {
+	// Токен в этих случаях не разбирается, поэтому auth, user и session не нужны.
+	interceptor := authenticate(nil, nil, nil)
+	handler := func(_ context.Context, _ any) (any, error) {
+		return "ok", nil
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		md     metadata.MD
+		code   codes.Code
+	}{
+		{
+			name:   "public method without token",
+			method: pb.Gophermart_Login_FullMethodName,
+			code:   codes.OK,
+		},
+		{
+			name:   "protected method without token",
+			method: pb.Gophermart_GetBalance_FullMethodName,
+			code:   codes.Unauthenticated,
+		},
+		{
+			name:   "protected method with empty bearer",
+			method: pb.Gophermart_ListOrders_FullMethodName,
+			md:     metadata.Pairs("authorization", "Bearer "),
+			code:   codes.Unauthenticated,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), test.md)
+			_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: test.method}, handler)
+			assert.Equal(t, test.code, status.Code(err))
+		})
+	}
+}
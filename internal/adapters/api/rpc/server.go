@@ -0,0 +1,68 @@
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative proto/gophermart.proto
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	pb "github.com/k0st1a/gophermart/internal/adapters/api/rpc/proto"
+	"github.com/k0st1a/gophermart/internal/pkg/auth"
+	"github.com/k0st1a/gophermart/internal/pkg/session"
+	"github.com/k0st1a/gophermart/internal/pkg/user"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+)
+
+type server struct {
+	server  *grpc.Server
+	address string
+}
+
+// New создаёт gRPC сервер с сервисом Gophermart. Методы, кроме Register и Login, требуют JWT токен
+// пользователя, см. authenticate.
+func New(address string, h pb.GophermartServer, a auth.UserAuthentication, u user.Managment,
+	s session.Managment) *server {
+	gs := grpc.NewServer(grpc.ChainUnaryInterceptor(logCalls, authenticate(a, u, s)))
+	pb.RegisterGophermartServer(gs, h)
+
+	return &server{
+		server:  gs,
+		address: address,
+	}
+}
+
+func (s *server) Run() error {
+	log.Info().Str("address", s.address).Msg("run grpc api")
+
+	l, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("listen error:%w", err)
+	}
+
+	err = s.server.Serve(l)
+	if err != nil {
+		return fmt.Errorf("serve error:%w", err)
+	}
+
+	return nil
+}
+
+// Shutdown дожидается завершения обрабатываемых вызовов, но не дольше, чем до отмены ctx,
+// после чего закрывает оставшиеся соединения.
+func (s *server) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return fmt.Errorf("graceful stop error:%w", ctx.Err())
+	}
+}
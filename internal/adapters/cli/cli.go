@@ -0,0 +1,278 @@
+// Package cli реализует команды gophermartctl для операций поддержки: просмотр пользователя, его заказов
+// и списаний, возврат заказа в очередь опроса, корректировку баланса, сброс пароля и блокировку.
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/pkg/admin"
+	"github.com/k0st1a/gophermart/internal/pkg/amount"
+	"github.com/k0st1a/gophermart/internal/pkg/auth"
+	"github.com/k0st1a/gophermart/internal/pkg/order"
+	"github.com/k0st1a/gophermart/internal/pkg/user"
+	"github.com/k0st1a/gophermart/internal/pkg/withdraw"
+	"github.com/k0st1a/gophermart/internal/ports"
+)
+
+// Usage описание команд gophermartctl.
+const Usage = `Usage: gophermartctl [flags] command [arguments]
+
+Commands:
+  user LOGIN                   данные пользователя
+  orders LOGIN                 заказы пользователя
+  withdrawals LOGIN            списания пользователя
+  requeue ORDER                вернуть заказ в статусе INVALID в очередь опроса системы расчёта начислений
+  credit LOGIN AMOUNT REASON   начислить баллы пользователю
+  debit LOGIN AMOUNT REASON    списать баллы у пользователя
+  reset-password LOGIN         задать пользователю новый случайный пароль и завершить его сессии
+  lock LOGIN                   заблокировать пользователя и завершить его сессии
+  unlock LOGIN                 разблокировать пользователя
+
+Flags:
+  -c   путь к файлу конфигурации сервера
+  -d   адрес подключения к базе данных
+  -l   уровень логирования`
+
+// ErrUsage неизвестная команда или неверное число аргументов.
+var ErrUsage = errors.New("invalid command or arguments")
+
+// passwordLength длина случайного пароля в байтах до кодирования в base64.
+const passwordLength = 12
+
+type handler struct {
+	out      io.Writer
+	auth     auth.UserAuthentication
+	user     user.Managment
+	order    order.Managment
+	withdraw withdraw.Managment
+	admin    admin.Managment
+}
+
+// NewHandler создаёт обработчик команд, который пишет результаты в out.
+func NewHandler(out io.Writer, a auth.UserAuthentication, u user.Managment, o order.Managment,
+	w withdraw.Managment, ad admin.Managment) *handler {
+	return &handler{
+		out:      out,
+		auth:     a,
+		user:     u,
+		order:    o,
+		withdraw: w,
+		admin:    ad,
+	}
+}
+
+type command struct {
+	run  func(h *handler, ctx context.Context, args []string) error
+	args int
+}
+
+var commands = map[string]command{
+	"user":           {run: (*handler).showUser, args: 1},
+	"orders":         {run: (*handler).listOrders, args: 1},
+	"withdrawals":    {run: (*handler).listWithdrawals, args: 1},
+	"requeue":        {run: (*handler).requeueOrder, args: 1},
+	"credit":         {run: (*handler).credit, args: 3},
+	"debit":          {run: (*handler).debit, args: 3},
+	"reset-password": {run: (*handler).resetPassword, args: 1},
+	"lock":           {run: (*handler).lock, args: 1},
+	"unlock":         {run: (*handler).unlock, args: 1},
+}
+
+// Run выполняет команду args[0] с аргументами args[1:].
+func (h *handler) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+
+	c, ok := commands[args[0]]
+	if !ok || len(args)-1 != c.args {
+		return ErrUsage
+	}
+
+	return c.run(h, ctx, args[1:])
+}
+
+func (h *handler) showUser(ctx context.Context, args []string) error {
+	u, err := h.lookupUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	locked := "no"
+	if !u.LockedAt.IsZero() {
+		locked = u.LockedAt.Format(time.RFC3339)
+	}
+
+	tw := tabwriter.NewWriter(h.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "id\t%d\n", u.ID)
+	fmt.Fprintf(tw, "login\t%s\n", u.Login)
+	fmt.Fprintf(tw, "role\t%s\n", u.Role)
+	fmt.Fprintf(tw, "balance\t%.2f\n", u.Balance)
+	fmt.Fprintf(tw, "withdrawn\t%.2f\n", u.Withdrawn)
+	fmt.Fprintf(tw, "locked\t%s\n", locked)
+
+	//nolint:wrapcheck //error of output
+	return tw.Flush()
+}
+
+func (h *handler) listOrders(ctx context.Context, args []string) error {
+	u, err := h.lookupUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	orders, err := h.order.List(ctx, u.ID, ports.ListFilter{})
+	if err != nil {
+		return fmt.Errorf("error of list orders:%w", err)
+	}
+
+	tw := tabwriter.NewWriter(h.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NUMBER\tSTATUS\tACCRUAL\tUPLOADED_AT")
+	for _, o := range orders {
+		fmt.Fprintf(tw, "%d\t%s\t%.2f\t%s\n", o.Number, o.Status, o.Accrual, o.UploadedAt.Format(time.RFC3339))
+	}
+
+	//nolint:wrapcheck //error of output
+	return tw.Flush()
+}
+
+func (h *handler) listWithdrawals(ctx context.Context, args []string) error {
+	u, err := h.lookupUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	withdrawals, err := h.withdraw.List(ctx, u.ID, ports.ListFilter{})
+	if err != nil {
+		return fmt.Errorf("error of list withdrawals:%w", err)
+	}
+
+	tw := tabwriter.NewWriter(h.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tORDER\tSUM\tPROCESSED_AT")
+	for _, w := range withdrawals {
+		fmt.Fprintf(tw, "%d\t%d\t%.2f\t%s\n", w.ID, w.Order, w.Sum, w.ProcessedAt.Format(time.RFC3339))
+	}
+
+	//nolint:wrapcheck //error of output
+	return tw.Flush()
+}
+
+func (h *handler) requeueOrder(ctx context.Context, args []string) error {
+	number, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid order number:%q", args[0])
+	}
+
+	err = h.order.Requeue(ctx, number)
+	if err != nil {
+		return fmt.Errorf("error of requeue order:%w", err)
+	}
+
+	fmt.Fprintf(h.out, "order %d requeued\n", number)
+	return nil
+}
+
+func (h *handler) credit(ctx context.Context, args []string) error {
+	return h.adjustBalance(ctx, args, 1)
+}
+
+func (h *handler) debit(ctx context.Context, args []string) error {
+	return h.adjustBalance(ctx, args, -1)
+}
+
+// adjustBalance начисляет (sign > 0) или списывает (sign < 0) баллы. Корректировка сохраняется без
+// идентификатора администратора, причина обязательна.
+func (h *handler) adjustBalance(ctx context.Context, args []string, sign float64) error {
+	cents, err := amount.Parse(args[1])
+	if err != nil || cents == 0 {
+		return fmt.Errorf("invalid amount:%q, expected positive number with at most two decimals", args[1])
+	}
+	sum := sign * amount.ToFloat(cents)
+
+	u, err := h.lookupUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	err = h.admin.AdjustBalance(ctx, 0, u.ID, sum, args[2])
+	if err != nil {
+		return fmt.Errorf("error of adjust balance:%w", err)
+	}
+
+	fmt.Fprintf(h.out, "balance of %s adjusted by %+.2f\n", u.Login, sum)
+	return nil
+}
+
+func (h *handler) resetPassword(ctx context.Context, args []string) error {
+	u, err := h.lookupUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	b := make([]byte, passwordLength)
+	_, err = rand.Read(b)
+	if err != nil {
+		return fmt.Errorf("failed to generate password:%w", err)
+	}
+	password := base64.RawURLEncoding.EncodeToString(b)
+
+	hash, err := h.auth.GeneratePasswordHash(password)
+	if err != nil {
+		return fmt.Errorf("error of generate password hash:%w", err)
+	}
+
+	err = h.user.ResetPassword(ctx, u.ID, hash)
+	if err != nil {
+		return fmt.Errorf("error of reset password:%w", err)
+	}
+
+	fmt.Fprintf(h.out, "new password of %s: %s\n", u.Login, password)
+	return nil
+}
+
+func (h *handler) lock(ctx context.Context, args []string) error {
+	u, err := h.lookupUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	err = h.user.Lock(ctx, u.ID)
+	if err != nil {
+		return fmt.Errorf("error of lock user:%w", err)
+	}
+
+	fmt.Fprintf(h.out, "user %s locked\n", u.Login)
+	return nil
+}
+
+func (h *handler) unlock(ctx context.Context, args []string) error {
+	u, err := h.lookupUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	err = h.user.Unlock(ctx, u.ID)
+	if err != nil {
+		return fmt.Errorf("error of unlock user:%w", err)
+	}
+
+	fmt.Fprintf(h.out, "user %s unlocked\n", u.Login)
+	return nil
+}
+
+func (h *handler) lookupUser(ctx context.Context, login string) (*admin.User, error) {
+	u, err := h.admin.GetUser(ctx, login)
+	if err != nil {
+		return nil, fmt.Errorf("error of get user %q:%w", login, err)
+	}
+
+	return u, nil
+}
@@ -0,0 +1,145 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/k0st1a/gophermart/internal/pkg/admin"
+	"github.com/k0st1a/gophermart/internal/pkg/auth"
+	"github.com/k0st1a/gophermart/internal/pkg/order"
+	"github.com/k0st1a/gophermart/internal/pkg/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubAdmin struct {
+	admin.Managment
+	users   map[string]*admin.User
+	adjusts []float64
+	reasons []string
+}
+
+func (s *stubAdmin) GetUser(_ context.Context, login string) (*admin.User, error) {
+	u, ok := s.users[login]
+	if !ok {
+		return nil, admin.ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (s *stubAdmin) AdjustBalance(_ context.Context, _, _ int64, amount float64, reason string) error {
+	s.adjusts = append(s.adjusts, amount)
+	s.reasons = append(s.reasons, reason)
+	return nil
+}
+
+type stubUser struct {
+	user.Managment
+	locked   map[int64]bool
+	password map[int64]string
+}
+
+func (s *stubUser) Lock(_ context.Context, userID int64) error {
+	s.locked[userID] = true
+	return nil
+}
+
+func (s *stubUser) Unlock(_ context.Context, userID int64) error {
+	s.locked[userID] = false
+	return nil
+}
+
+func (s *stubUser) ResetPassword(_ context.Context, userID int64, passwordHash string) error {
+	s.password[userID] = passwordHash
+	return nil
+}
+
+type stubOrder struct {
+	order.Managment
+	requeued []int64
+}
+
+func (s *stubOrder) Requeue(_ context.Context, orderID int64) error {
+	if orderID != 12345678903 {
+		return order.ErrNotFound
+	}
+	s.requeued = append(s.requeued, orderID)
+	return nil
+}
+
+type stubAuth struct {
+	auth.UserAuthentication
+}
+
+func (stubAuth) GeneratePasswordHash(password string) (string, error) {
+	return "hash:" + password, nil
+}
+
+func TestRun(t *testing.T) {
+	a := &stubAdmin{users: map[string]*admin.User{
+		"alice": {ID: 7, Login: "alice", Role: "user", Balance: 500},
+	}}
+	u := &stubUser{locked: map[int64]bool{}, password: map[int64]string{}}
+	o := &stubOrder{}
+	out := &bytes.Buffer{}
+	h := NewHandler(out, stubAuth{}, u, o, nil, a)
+	ctx := context.Background()
+
+	t.Run("usage", func(t *testing.T) {
+		assert.ErrorIs(t, h.Run(ctx, nil), ErrUsage)
+		assert.ErrorIs(t, h.Run(ctx, []string{"unknown"}), ErrUsage)
+		assert.ErrorIs(t, h.Run(ctx, []string{"lock"}), ErrUsage)
+		assert.ErrorIs(t, h.Run(ctx, []string{"credit", "alice", "10"}), ErrUsage)
+	})
+
+	t.Run("user", func(t *testing.T) {
+		out.Reset()
+		require.NoError(t, h.Run(ctx, []string{"user", "alice"}))
+		assert.Contains(t, out.String(), "balance    500.00")
+		assert.Contains(t, out.String(), "locked     no")
+
+		assert.ErrorIs(t, h.Run(ctx, []string{"user", "bob"}), admin.ErrUserNotFound)
+	})
+
+	t.Run("credit and debit", func(t *testing.T) {
+		require.NoError(t, h.Run(ctx, []string{"credit", "alice", "10.5", "lost accrual"}))
+		require.NoError(t, h.Run(ctx, []string{"debit", "alice", "3", "duplicate accrual"}))
+		assert.Equal(t, []float64{10.5, -3}, a.adjusts)
+		assert.Equal(t, []string{"lost accrual", "duplicate accrual"}, a.reasons)
+
+		assert.Error(t, h.Run(ctx, []string{"debit", "alice", "-3", "negative"}))
+		assert.Error(t, h.Run(ctx, []string{"credit", "alice", "ten", "not a number"}))
+		assert.Error(t, h.Run(ctx, []string{"credit", "alice", "NaN", "not a number"}))
+		assert.Error(t, h.Run(ctx, []string{"credit", "alice", "Inf", "infinity"}))
+		assert.Error(t, h.Run(ctx, []string{"credit", "alice", "infinity", "infinity"}))
+		assert.Error(t, h.Run(ctx, []string{"credit", "alice", "1e400", "overflow"}))
+		assert.Error(t, h.Run(ctx, []string{"credit", "alice", "0.001", "too precise"}))
+		assert.Error(t, h.Run(ctx, []string{"credit", "alice", "0", "zero"}))
+		assert.Len(t, a.adjusts, 2)
+	})
+
+	t.Run("requeue", func(t *testing.T) {
+		require.NoError(t, h.Run(ctx, []string{"requeue", "12345678903"}))
+		assert.Equal(t, []int64{12345678903}, o.requeued)
+
+		assert.ErrorIs(t, h.Run(ctx, []string{"requeue", "79927398713"}), order.ErrNotFound)
+		assert.Error(t, h.Run(ctx, []string{"requeue", "abc"}))
+	})
+
+	t.Run("reset password", func(t *testing.T) {
+		out.Reset()
+		require.NoError(t, h.Run(ctx, []string{"reset-password", "alice"}))
+		password := bytes.TrimPrefix(bytes.TrimSpace(out.Bytes()), []byte("new password of alice: "))
+		assert.Len(t, password, 16)
+		assert.Equal(t, "hash:"+string(password), u.password[7])
+	})
+
+	t.Run("lock and unlock", func(t *testing.T) {
+		require.NoError(t, h.Run(ctx, []string{"lock", "alice"}))
+		assert.True(t, u.locked[7])
+
+		require.NoError(t, h.Run(ctx, []string{"unlock", "alice"}))
+		assert.False(t, u.locked[7])
+	})
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Ping проверяет, что из пула можно получить соединение и БД отвечает.
+func (d *db) Ping(ctx context.Context) error {
+	err := d.pool.Ping(ctx)
+	if err != nil {
+		return fmt.Errorf("ping error:%w", err)
+	}
+
+	return nil
+}
+
+// CheckMigrations проверяет, что в БД применены все миграции, встроенные в сервер, и последняя из них
+// не завершилась ошибкой.
+func (d *db) CheckMigrations(ctx context.Context) error {
+	expected, err := latestMigrationVersion()
+	if err != nil {
+		return err
+	}
+
+	var version uint
+	var dirty bool
+	err = d.pool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations").Scan(&version, &dirty)
+	if err != nil {
+		return fmt.Errorf("query error of get migration version:%w", err)
+	}
+
+	if dirty {
+		return fmt.Errorf("migration %d is dirty", version)
+	}
+
+	if version != expected {
+		return fmt.Errorf("migration version is %d, expected %d", version, expected)
+	}
+
+	return nil
+}
+
+// latestMigrationVersion возвращает версию последней встроенной миграции.
+func latestMigrationVersion() (uint, error) {
+	d, err := iofs.New(migrationsDir, "migrations")
+	if err != nil {
+		return 0, fmt.Errorf("failed to return an iofs driver: %w", err)
+	}
+	defer func() {
+		_ = d.Close()
+	}()
+
+	version, err := d.First()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read first migration: %w", err)
+	}
+
+	for {
+		next, err := d.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read migration after %d: %w", version, err)
+		}
+		version = next
+	}
+}
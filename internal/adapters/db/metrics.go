@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+)
+
+// backlogQueryTimeout ограничивает время запроса размера очереди опроса при сборе метрик.
+const backlogQueryTimeout = 5 * time.Second
+
+var (
+	poolAcquiredConns = prometheus.NewDesc("gophermart_db_pool_acquired_conns",
+		"Number of currently acquired connections in the pool.", nil, nil)
+	poolIdleConns = prometheus.NewDesc("gophermart_db_pool_idle_conns",
+		"Number of currently idle connections in the pool.", nil, nil)
+	poolTotalConns = prometheus.NewDesc("gophermart_db_pool_total_conns",
+		"Total number of connections currently in the pool.", nil, nil)
+	poolMaxConns = prometheus.NewDesc("gophermart_db_pool_max_conns",
+		"Maximum size of the pool.", nil, nil)
+	poolAcquireCount = prometheus.NewDesc("gophermart_db_pool_acquires_total",
+		"Cumulative count of successful acquires from the pool.", nil, nil)
+	poolEmptyAcquireCount = prometheus.NewDesc("gophermart_db_pool_empty_acquires_total",
+		"Cumulative count of acquires that waited for a connection because the pool was empty.", nil, nil)
+	poolCanceledAcquireCount = prometheus.NewDesc("gophermart_db_pool_canceled_acquires_total",
+		"Cumulative count of acquires canceled by a context.", nil, nil)
+	poolAcquireDuration = prometheus.NewDesc("gophermart_db_pool_acquire_duration_seconds_total",
+		"Total time spent waiting for connections from the pool.", nil, nil)
+	ordersBacklog = prometheus.NewDesc("gophermart_orders_backlog",
+		"Number of orders waiting for accrual polling by status.", []string{"status"}, nil)
+)
+
+// collector отдаёт статистику пула соединений и размер очереди заказов, ожидающих опроса системы расчёта
+// начислений. Значения читаются в момент сбора метрик.
+type collector struct {
+	db *db
+}
+
+// Collector возвращает коллектор метрик БД для регистрации в prometheus.Registerer.
+func (d *db) Collector() prometheus.Collector {
+	return &collector{db: d}
+}
+
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredConns
+	ch <- poolIdleConns
+	ch <- poolTotalConns
+	ch <- poolMaxConns
+	ch <- poolAcquireCount
+	ch <- poolEmptyAcquireCount
+	ch <- poolCanceledAcquireCount
+	ch <- poolAcquireDuration
+	ch <- ordersBacklog
+}
+
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	s := c.db.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireCount, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquireCount, prometheus.CounterValue,
+		float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceledAcquireCount, prometheus.CounterValue,
+		float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+
+	ctx, cancel := context.WithTimeout(context.Background(), backlogQueryTimeout)
+	defer cancel()
+
+	backlog, err := c.db.countOrdersBacklog(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("error of count orders backlog")
+		ch <- prometheus.NewInvalidMetric(ordersBacklog, err)
+		return
+	}
+
+	for _, status := range []string{"NEW", "PROCESSING"} {
+		ch <- prometheus.MustNewConstMetric(ordersBacklog, prometheus.GaugeValue, float64(backlog[status]), status)
+	}
+}
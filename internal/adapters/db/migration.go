@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"embed"
 	"errors"
 	"fmt"
@@ -8,28 +9,135 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
 )
 
 //go:embed migrations/*.sql
 var migrationsDir embed.FS
 
-func runMigrations(dsn string) error {
+// migrationLockKey ключ рекомендательной блокировки PostgreSQL, которую держит migrator, чтобы миграции
+// из нескольких процессов (реплик сервера, gophermart migrate) не выполнялись одновременно.
+//
+// Драйвер postgres golang-migrate сам берёт рекомендательную блокировку, но только на время одного вызова
+// Up, Steps или Force. migrationLockKey удерживается всё время жизни migrator, от NewMigrator до Close:
+// так между чтением версии и последующими Up, Down или Force, например в gophermart migrate force,
+// миграции другого процесса не выполняются.
+const migrationLockKey int64 = 0x676f706865726d61 // "gopherma"
+
+// migrator применяет и откатывает миграции, встроенные в сервер.
+type migrator struct {
+	m *migrate.Migrate
+	// lock соединение, удерживающее блокировку migrationLockKey до вызова Close.
+	lock *pgx.Conn
+}
+
+// NewMigrator создаёт migrator для БД с адресом dsn. Ожидает рекомендательную блокировку миграций, пока её
+// держит другой процесс, не дольше чем до отмены ctx. После использования migrator нужно закрыть вызовом
+// Close, чтобы снять блокировку.
+func NewMigrator(ctx context.Context, dsn string) (*migrator, error) {
+	lock, err := lockMigrations(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+
 	d, err := iofs.New(migrationsDir, "migrations")
 	if err != nil {
-		return fmt.Errorf("failed to return an iofs driver: %w", err)
+		_ = lock.Close(ctx)
+		return nil, fmt.Errorf("failed to return an iofs driver: %w", err)
 	}
 
 	m, err := migrate.NewWithSourceInstance("iofs", d, dsn)
 	if err != nil {
-		return fmt.Errorf("failed to get a new migrate instance: %w", err)
+		_ = lock.Close(ctx)
+		return nil, fmt.Errorf("failed to get a new migrate instance: %w", err)
 	}
 
-	err = m.Up()
+	return &migrator{m: m, lock: lock}, nil
+}
+
+// lockMigrations открывает соединение с БД и берёт на нём блокировку migrationLockKey. Блокировка
+// снимается при закрытии соединения, в том числе при аварийном завершении процесса.
+func lockMigrations(ctx context.Context, dsn string) (*pgx.Conn, error) {
+	conn, err := pgx.Connect(ctx, dsn)
 	if err != nil {
-		if !errors.Is(err, migrate.ErrNoChange) {
-			return fmt.Errorf("failed to apply migrations to the DB: %w", err)
-		}
+		return nil, fmt.Errorf("failed to connect for migration lock: %w", err)
+	}
+
+	var locked bool
+	err = conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", migrationLockKey).Scan(&locked)
+	if err == nil && !locked {
+		log.Ctx(ctx).Info().Msg("waiting for migration lock held by another process")
+		_, err = conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey)
+	}
+	if err != nil {
+		_ = conn.Close(context.Background())
+		return nil, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+
+	return conn, nil
+}
+
+// Up применяет все ещё не применённые миграции.
+func (m *migrator) Up() error {
+	err := m.m.Up()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to apply migrations to the DB: %w", err)
 	}
 
 	return nil
 }
+
+// Down откатывает steps последних применённых миграций.
+func (m *migrator) Down(steps int) error {
+	err := m.m.Steps(-steps)
+	if err != nil {
+		return fmt.Errorf("failed to roll back %d migrations: %w", steps, err)
+	}
+
+	return nil
+}
+
+// Version возвращает версию последней применённой миграции и признак того, что она завершилась ошибкой.
+// Если миграции ещё не применялись, возвращается версия 0.
+func (m *migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get migration version: %w", err)
+	}
+
+	return version, dirty, nil
+}
+
+// Force записывает версию миграции version без её применения и снимает признак ошибки. Используется, чтобы
+// вручную исправить БД после миграции, завершившейся ошибкой.
+func (m *migrator) Force(version int) error {
+	err := m.m.Force(version)
+	if err != nil {
+		return fmt.Errorf("failed to force migration version %d: %w", version, err)
+	}
+
+	return nil
+}
+
+// Close закрывает соединения с БД и снимает блокировку миграций.
+func (m *migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	lockErr := m.lock.Close(context.Background())
+	return errors.Join(srcErr, dbErr, lockErr)
+}
+
+func runMigrations(ctx context.Context, dsn string) error {
+	m, err := NewMigrator(ctx, dsn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = m.Close()
+	}()
+
+	return m.Up()
+}
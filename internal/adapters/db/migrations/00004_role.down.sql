@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE IF EXISTS balance_adjustments;
+
+ALTER TABLE users DROP COLUMN IF EXISTS role;
+
+DROP TYPE IF EXISTS role_type;
+
+COMMIT;
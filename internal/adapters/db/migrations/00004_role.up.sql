@@ -0,0 +1,16 @@
+BEGIN;
+
+CREATE TYPE role_type AS ENUM ('user', 'support', 'admin');
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role role_type NOT NULL DEFAULT 'user';
+
+CREATE TABLE IF NOT EXISTS balance_adjustments (
+    id          bigserial PRIMARY KEY,
+    user_id     bigint NOT NULL REFERENCES users (id) ON DELETE RESTRICT,
+    admin_id    bigint NULL REFERENCES users (id) ON DELETE RESTRICT,
+    amount      double precision NOT NULL,
+    reason      TEXT NOT NULL,
+    created_at  timestamp NOT NULL DEFAULT NOW()
+);
+
+COMMIT;
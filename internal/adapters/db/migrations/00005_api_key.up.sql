@@ -0,0 +1,16 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           bigserial PRIMARY KEY,
+    user_id      bigint NOT NULL REFERENCES users (id) ON DELETE RESTRICT,
+    name         TEXT NOT NULL,
+    hash         TEXT UNIQUE NOT NULL,
+    scopes       TEXT[] NOT NULL,
+    created_at   timestamp NOT NULL DEFAULT NOW(),
+    last_used_at timestamp NULL,
+    revoked_at   timestamp NULL
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
+
+COMMIT;
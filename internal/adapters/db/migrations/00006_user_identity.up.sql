@@ -0,0 +1,11 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    issuer     TEXT NOT NULL,
+    subject    TEXT NOT NULL,
+    user_id    bigint NOT NULL REFERENCES users (id) ON DELETE RESTRICT,
+    created_at timestamp NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (issuer, subject)
+);
+
+COMMIT;
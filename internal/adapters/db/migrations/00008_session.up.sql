@@ -0,0 +1,15 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id            bigserial PRIMARY KEY,
+    user_id       bigint NOT NULL REFERENCES users (id) ON DELETE RESTRICT,
+    user_agent    TEXT NOT NULL DEFAULT '',
+    ip            TEXT NOT NULL DEFAULT '',
+    created_at    timestamp NOT NULL DEFAULT NOW(),
+    last_seen_at  timestamp NOT NULL DEFAULT NOW(),
+    terminated_at timestamp NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS orders_user_id_uploaded_at_id_idx ON orders (user_id, uploaded_at, id);
+CREATE INDEX IF NOT EXISTS withdrawals_user_id_processed_at_id_idx ON withdrawals (user_id, processed_at, id);
+
+COMMIT;
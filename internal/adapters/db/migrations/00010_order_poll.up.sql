@@ -0,0 +1,13 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS order_polls (
+    id        bigserial PRIMARY KEY,
+    order_id  bigint NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+    status    TEXT NOT NULL,
+    accrual   double precision NULL,
+    polled_at timestamp NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS order_polls_order_id_polled_at_idx ON order_polls (order_id, polled_at);
+
+COMMIT;
@@ -0,0 +1,33 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id         bigserial PRIMARY KEY,
+    user_id    bigint NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    url        TEXT NOT NULL,
+    secret     TEXT NOT NULL,
+    events     TEXT[] NOT NULL,
+    created_at timestamp NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS webhooks_user_id_idx ON webhooks (user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id               bigserial PRIMARY KEY,
+    webhook_id       bigint NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    event_type       TEXT NOT NULL,
+    payload          jsonb NOT NULL,
+    status           TEXT NOT NULL DEFAULT 'pending',
+    attempts         integer NOT NULL DEFAULT 0,
+    next_attempt_at  timestamp NOT NULL DEFAULT NOW(),
+    last_attempt_at  timestamp NULL,
+    last_status_code integer NULL,
+    last_error       TEXT NULL,
+    created_at       timestamp NOT NULL DEFAULT NOW(),
+    delivered_at     timestamp NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_webhook_id_id_idx ON webhook_deliveries (webhook_id, id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_pending_idx ON webhook_deliveries (next_attempt_at)
+    WHERE status = 'pending';
+
+COMMIT;
@@ -4,24 +4,58 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/k0st1a/gophermart/internal/pkg/logging"
 	"github.com/k0st1a/gophermart/internal/ports"
 	"github.com/rs/zerolog/log"
 )
 
+const userEventsChannel = "user_events"
+
 type db struct {
 	pool *pgxpool.Pool
 }
 
-func NewDB(ctx context.Context, dsn string) (*db, error) {
-	err := runMigrations(dsn)
+// Options настройки пула соединений с БД.
+type Options struct {
+	// MaxConns максимальное число соединений в пуле.
+	MaxConns int32
+	// MinConns число соединений, которые пул держит открытыми.
+	MinConns int32
+	// AutoMigrate применяет миграции при создании подключения к БД.
+	AutoMigrate bool
+	// MigrationDSN адрес подключения к БД для миграций, например с правами владельца схемы, пусто - адрес
+	// подключения пула.
+	MigrationDSN string
+}
+
+// NewDB создаёт пул соединений с БД с адресом dsn. Если задан opts.AutoMigrate, сначала применяет миграции.
+func NewDB(ctx context.Context, dsn string, opts Options) (*db, error) {
+	if opts.AutoMigrate {
+		migrationDSN := opts.MigrationDSN
+		if migrationDSN == "" {
+			migrationDSN = dsn
+		}
+
+		err := runMigrations(ctx, migrationDSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run DB migrations: %w", err)
+		}
+	}
+
+	config, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
-		return nil, fmt.Errorf("failed to run DB migrations: %w", err)
+		return nil, fmt.Errorf("failed to parse dsn: %w", err)
 	}
+	config.ConnConfig.Tracer = queryTracer{}
+	config.MaxConns = opts.MaxConns
+	config.MinConns = opts.MinConns
 
-	pool, err := pgxpool.New(ctx, dsn)
+	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a connection pool: %w", err)
 	}
@@ -40,11 +74,11 @@ func (d *db) BeginTx(ctx context.Context) (pgx.Tx, error) {
 	return tx, nil
 }
 
-func (d *db) CreateUser(ctx context.Context, login, password string) (int64, error) {
-	log.Printf("CreateUser, login:%s, password:%s", login, password)
+func (d *db) CreateUser(ctx context.Context, tx pgx.Tx, login, password string) (int64, error) {
+	log.Ctx(ctx).Debug().Str("login", login).Msg("CreateUser")
 	var id int64
 
-	err := d.pool.QueryRow(ctx,
+	err := tx.QueryRow(ctx,
 		"INSERT INTO users (login,password) VALUES($1,$2) "+
 			"ON CONFLICT DO NOTHING "+
 			"RETURNING id",
@@ -61,25 +95,29 @@ func (d *db) CreateUser(ctx context.Context, login, password string) (int64, err
 	return id, nil
 }
 
-func (d *db) GetUserIDAndPassword(ctx context.Context, login string) (int64, string, error) {
-	log.Printf("GetUserIDAndPassword, login:%s", login)
+// GetUserIDAndPassword возвращает идентификатор, хеш пароля и признак блокировки пользователя. О блокировке
+// сообщается только после проверки пароля, чтобы не раскрывать состояние аккаунта без учётных данных.
+func (d *db) GetUserIDAndPassword(ctx context.Context, login string) (int64, string, bool, error) {
+	log.Ctx(ctx).Debug().Str("login", login).Msg("GetUserIDAndPassword")
 	var id int64
 	var password string
+	var locked bool
 
-	err := d.pool.QueryRow(ctx, "SELECT id, password FROM users WHERE login = $1", login).Scan(&id, &password)
+	err := d.pool.QueryRow(ctx, "SELECT id, password, locked_at IS NOT NULL FROM users WHERE login = $1", login).
+		Scan(&id, &password, &locked)
 	if errors.Is(err, pgx.ErrNoRows) {
-		return 0, "", ports.ErrUserNotFound
+		return 0, "", false, ports.ErrUserNotFound
 	}
 
 	if err != nil {
-		return 0, "", fmt.Errorf("failed to get user id and password:%w", err)
+		return 0, "", false, fmt.Errorf("failed to get user id and password:%w", err)
 	}
 
-	return id, password, nil
+	return id, password, locked, nil
 }
 
 func (d *db) GetBalanceAndWithdrawn(ctx context.Context, userID int64) (float64, float64, error) {
-	log.Printf("GetBalanceAndWithdrawn, userID:%v", userID)
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("GetBalanceAndWithdrawn")
 	var (
 		balance   float64
 		withdrawn float64
@@ -94,8 +132,281 @@ func (d *db) GetBalanceAndWithdrawn(ctx context.Context, userID int64) (float64,
 	return balance, withdrawn, nil
 }
 
+func (d *db) GetUserRole(ctx context.Context, userID int64) (string, error) {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("GetUserRole")
+	var role string
+
+	err := d.pool.QueryRow(ctx, "SELECT role FROM users WHERE id = $1", userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", ports.ErrUserNotFound
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("query error of get user role:%w", err)
+	}
+
+	return role, nil
+}
+
+func (d *db) GetUserIDByIdentity(ctx context.Context, issuer, subject string) (int64, error) {
+	log.Ctx(ctx).Debug().Str("issuer", issuer).Str("subject", subject).Msg("GetUserIDByIdentity")
+	var id int64
+	var locked bool
+
+	err := d.pool.QueryRow(ctx,
+		"SELECT i.user_id, u.locked_at IS NOT NULL FROM user_identities i JOIN users u ON u.id = i.user_id "+
+			"WHERE i.issuer = $1 AND i.subject = $2", issuer, subject).Scan(&id, &locked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ports.ErrUserNotFound
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("query error of get user id by identity:%w", err)
+	}
+
+	if locked {
+		return 0, ports.ErrUserLocked
+	}
+
+	return id, nil
+}
+
+// CreateUserIdentity привязывает identity к пользователю. Если identity уже привязана, в том числе параллельной
+// транзакцией, возвращает ports.ErrIdentityAlreadyAssigned.
+func (d *db) CreateUserIdentity(ctx context.Context, tx pgx.Tx, userID int64, issuer, subject string) error {
+	log.Ctx(ctx).Debug().
+		Int64(logging.FieldTargetUserID, userID).
+		Str("issuer", issuer).
+		Str("subject", subject).
+		Msg("CreateUserIdentity")
+	var id int64
+
+	err := tx.QueryRow(ctx,
+		"INSERT INTO user_identities (issuer, subject, user_id) VALUES ($1, $2, $3) "+
+			"ON CONFLICT DO NOTHING "+
+			"RETURNING user_id",
+		issuer, subject, userID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ports.ErrIdentityAlreadyAssigned
+	}
+
+	if err != nil {
+		return fmt.Errorf("query error of create user identity:%w", err)
+	}
+
+	return nil
+}
+
+// IsUserDeleted сообщает, что пользователь удалил аккаунт.
+func (d *db) IsUserDeleted(ctx context.Context, userID int64) (bool, error) {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("IsUserDeleted")
+	var deleted bool
+
+	err := d.pool.QueryRow(ctx,
+		"SELECT deleted_at IS NOT NULL FROM users WHERE id = $1", userID).Scan(&deleted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ports.ErrUserNotFound
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("query error of is user deleted:%w", err)
+	}
+
+	return deleted, nil
+}
+
+// AnonymizeUser заменяет логин псевдонимом и стирает пароль. Сама строка пользователя остаётся,
+// так как на неё ссылаются заказы и списания.
+func (d *db) AnonymizeUser(ctx context.Context, tx pgx.Tx, userID int64, login string) error {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("AnonymizeUser")
+	var id int64
+
+	err := tx.QueryRow(ctx,
+		"UPDATE ONLY users SET login = $1, password = '', deleted_at = NOW() "+
+			"WHERE id = $2 AND deleted_at IS NULL RETURNING id",
+		login, userID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ports.ErrUserNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("query error of anonymize user:%w", err)
+	}
+
+	return nil
+}
+
+func (d *db) RevokeAPIKeys(ctx context.Context, tx pgx.Tx, userID int64) error {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("RevokeAPIKeys")
+
+	_, err := tx.Exec(ctx,
+		"UPDATE ONLY api_keys SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		return fmt.Errorf("query error of revoke api keys:%w", err)
+	}
+
+	return nil
+}
+
+func (d *db) DeleteUserIdentities(ctx context.Context, tx pgx.Tx, userID int64) error {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("DeleteUserIdentities")
+
+	_, err := tx.Exec(ctx, "DELETE FROM user_identities WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("query error of delete user identities:%w", err)
+	}
+
+	return nil
+}
+
+func (d *db) TerminateSessions(ctx context.Context, tx pgx.Tx, userID int64) error {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("TerminateSessions")
+
+	_, err := tx.Exec(ctx,
+		"UPDATE ONLY sessions SET terminated_at = NOW() WHERE user_id = $1 AND terminated_at IS NULL", userID)
+	if err != nil {
+		return fmt.Errorf("query error of terminate sessions:%w", err)
+	}
+
+	return nil
+}
+
+// SetUserLocked блокирует (locked) или разблокирует пользователя.
+func (d *db) SetUserLocked(ctx context.Context, tx pgx.Tx, userID int64, locked bool) error {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Bool("locked", locked).Msg("SetUserLocked")
+	var id int64
+
+	err := tx.QueryRow(ctx,
+		"UPDATE ONLY users SET locked_at = CASE WHEN $2 THEN COALESCE(locked_at, NOW()) END "+
+			"WHERE id = $1 AND deleted_at IS NULL RETURNING id",
+		userID, locked).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ports.ErrUserNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("query error of set user locked:%w", err)
+	}
+
+	return nil
+}
+
+func (d *db) UpdatePassword(ctx context.Context, tx pgx.Tx, userID int64, password string) error {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("UpdatePassword")
+	var id int64
+
+	err := tx.QueryRow(ctx,
+		"UPDATE ONLY users SET password = $2 WHERE id = $1 AND deleted_at IS NULL RETURNING id",
+		userID, password).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ports.ErrUserNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("query error of update password:%w", err)
+	}
+
+	return nil
+}
+
+func (d *db) GetUserByID(ctx context.Context, userID int64) (*ports.User, error) {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("GetUserByID")
+	u := ports.User{}
+
+	err := d.pool.QueryRow(ctx,
+		"SELECT id, login, role, balance, withdrawn, locked_at FROM users WHERE id = $1", userID).
+		Scan(&u.ID, &u.Login, &u.Role, &u.Balance, &u.Withdrawn, &u.LockedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ports.ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("query error of get user by id:%w", err)
+	}
+
+	return &u, nil
+}
+
+// GetBalanceHistory собирает историю изменений баланса из начислений по заказам, списаний
+// и ручных корректировок.
+func (d *db) GetBalanceHistory(ctx context.Context, userID int64) ([]ports.BalanceEntry, error) {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("GetBalanceHistory")
+	var entries []ports.BalanceEntry
+
+	rows, err := d.pool.Query(ctx,
+		"SELECT 'accrual' AS type, accrual AS amount, id AS order_id, '' AS reason, uploaded_at AS at "+
+			"FROM orders WHERE user_id = $1 AND status = 'PROCESSED' AND accrual > 0 "+
+			"UNION ALL "+
+			"SELECT 'withdrawal', -sum, order_id, '', processed_at "+
+			"FROM withdrawals WHERE user_id = $1 "+
+			"UNION ALL "+
+			"SELECT 'adjustment', amount, NULL, reason, created_at "+
+			"FROM balance_adjustments WHERE user_id = $1 "+
+			"ORDER BY at",
+		userID)
+	if err != nil {
+		return entries, fmt.Errorf("query error of get balance history:%w", err)
+	}
+
+	for rows.Next() {
+		var e ports.BalanceEntry
+		err = rows.Scan(
+			&e.Type,
+			&e.Amount,
+			&e.Order,
+			&e.Reason,
+			&e.At,
+		)
+		if err != nil {
+			return entries, fmt.Errorf("scan error of get balance history:%w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return entries, fmt.Errorf("error of get balance history:%w", err)
+	}
+
+	return entries, nil
+}
+
+func (d *db) GetUserByLogin(ctx context.Context, login string) (*ports.User, error) {
+	log.Ctx(ctx).Debug().Str("login", login).Msg("GetUserByLogin")
+	u := ports.User{}
+
+	err := d.pool.QueryRow(ctx,
+		"SELECT id, login, role, balance, withdrawn, locked_at FROM users WHERE login = $1", login).
+		Scan(&u.ID, &u.Login, &u.Role, &u.Balance, &u.Withdrawn, &u.LockedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ports.ErrUserNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("query error of get user by login:%w", err)
+	}
+
+	return &u, nil
+}
+
+func (d *db) CreateBalanceAdjustment(ctx context.Context, tx pgx.Tx, userID, adminID int64, amount float64,
+	reason string) error {
+	var id int64
+
+	err := tx.QueryRow(ctx,
+		"INSERT INTO balance_adjustments (user_id, admin_id, amount, reason) VALUES ($1, NULLIF($2::bigint, 0), $3, $4) "+
+			"RETURNING id",
+		userID, adminID, amount, reason).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("query error of create balance adjustment:%w", err)
+	}
+
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Int64("id", id).Msg("Created balance adjustment")
+	return nil
+}
+
 func (d *db) GetBalanceAndWithdrawnWithBlock(ctx context.Context, tx pgx.Tx, userID int64) (float64, float64, error) {
-	log.Printf("GetBalanceAndWithdrawnWithBlock, userID:%v", userID)
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("GetBalanceAndWithdrawnWithBlock")
 	var (
 		balance   float64
 		withdrawn float64
@@ -111,7 +422,7 @@ func (d *db) GetBalanceAndWithdrawnWithBlock(ctx context.Context, tx pgx.Tx, use
 }
 
 func (d *db) GetBalanceWithBlock(ctx context.Context, tx pgx.Tx, userID int64) (float64, error) {
-	log.Printf("GetBalanceWithBlock, userID:%v", userID)
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("GetBalanceWithBlock")
 	var balance float64
 
 	err := tx.QueryRow(ctx,
@@ -150,7 +461,7 @@ func (d *db) UpdateBalance(ctx context.Context, tx pgx.Tx, userID int64, balance
 }
 
 func (d *db) GetUserIDByOrder(ctx context.Context, orderID int64) (int64, error) {
-	log.Printf("GetUserIDByOrder, orderID:%v", orderID)
+	log.Ctx(ctx).Debug().Int64("order_id", orderID).Msg("GetUserIDByOrder")
 	var userID int64
 
 	err := d.pool.QueryRow(ctx, "SELECT user_id FROM orders WHERE id = $1", orderID).Scan(&userID)
@@ -166,7 +477,7 @@ func (d *db) GetUserIDByOrder(ctx context.Context, orderID int64) (int64, error)
 }
 
 func (d *db) GetUserIDByOrderWithBlock(ctx context.Context, tx pgx.Tx, orderID int64) (int64, error) {
-	log.Printf("GetUserIDByOrderWithBlock, orderID:%v", orderID)
+	log.Ctx(ctx).Debug().Int64("order_id", orderID).Msg("GetUserIDByOrderWithBlock")
 	var userID int64
 
 	err := tx.QueryRow(ctx, "SELECT user_id FROM orders WHERE id = $1 FOR UPDATE", orderID).Scan(&userID)
@@ -182,7 +493,7 @@ func (d *db) GetUserIDByOrderWithBlock(ctx context.Context, tx pgx.Tx, orderID i
 }
 
 func (d *db) CreateOrder(ctx context.Context, userID, orderID int64) error {
-	log.Printf("CreateOrder, userID:%v, orderID:%v", userID, orderID)
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Int64("order_id", orderID).Msg("CreateOrder")
 	var id int64
 
 	err := d.pool.QueryRow(ctx,
@@ -191,12 +502,58 @@ func (d *db) CreateOrder(ctx context.Context, userID, orderID int64) error {
 		return fmt.Errorf("failed to create order:%w", err)
 	}
 
-	log.Printf("Created order, id:%v", id)
+	log.Ctx(ctx).Debug().Int64("id", id).Msg("Created order")
 	return nil
 }
 
+// CreateOrders создаёт заказы, которых ещё нет в БД, и возвращает номера созданных.
+func (d *db) CreateOrders(ctx context.Context, tx pgx.Tx, userID int64, orderIDs []int64) ([]int64, error) {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Int("count", len(orderIDs)).Msg("CreateOrders")
+	var created []int64
+
+	rows, err := tx.Query(ctx,
+		"INSERT INTO orders (id, status, user_id) SELECT unnest($1::bigint[]), 'NEW', $2 "+
+			"ON CONFLICT DO NOTHING RETURNING id",
+		orderIDs, userID)
+	if err != nil {
+		return created, fmt.Errorf("query error of create orders:%w", err)
+	}
+
+	created, err = pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return created, fmt.Errorf("error of create orders:%w", err)
+	}
+
+	return created, nil
+}
+
+func (d *db) GetUserIDsByOrders(ctx context.Context, tx pgx.Tx, orderIDs []int64) (map[int64]int64, error) {
+	users := make(map[int64]int64, len(orderIDs))
+
+	rows, err := tx.Query(ctx, "SELECT id, user_id FROM orders WHERE id = ANY($1)", orderIDs)
+	if err != nil {
+		return users, fmt.Errorf("query error of get user ids by orders:%w", err)
+	}
+
+	for rows.Next() {
+		var orderID, userID int64
+		err = rows.Scan(&orderID, &userID)
+		if err != nil {
+			return users, fmt.Errorf("scan error of get user ids by orders:%w", err)
+		}
+		users[orderID] = userID
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return users, fmt.Errorf("error of get user ids by orders:%w", err)
+	}
+
+	return users, nil
+}
+
 func (d *db) UpdateOrder(ctx context.Context, tx pgx.Tx, orderID int64, status string, accrual float64) error {
-	log.Printf("UpdateOrder, orderID:%v, status:%v, accrual:%v", orderID, status, accrual)
+	log.Ctx(ctx).Debug().Int64("order_id", orderID).Str("status", status).Float64("accrual", accrual).Msg("UpdateOrder")
 	var id int64
 
 	err := tx.QueryRow(ctx, "UPDATE ONLY orders SET accrual = $1, status = $2 WHERE id = $3 RETURNING id",
@@ -205,17 +562,18 @@ func (d *db) UpdateOrder(ctx context.Context, tx pgx.Tx, orderID int64, status s
 		return fmt.Errorf("query error of update order:%w", err)
 	}
 
-	log.Printf("Updated order, id:%v", id)
+	log.Ctx(ctx).Debug().Int64("id", id).Msg("Updated order")
 	return nil
 }
 
-func (d *db) GetOrders(ctx context.Context, userID int64) ([]ports.Order, error) {
+func (d *db) GetOrders(ctx context.Context, userID int64, filter ports.ListFilter) ([]ports.Order, error) {
 	var orders []ports.Order
 
-	rows, err := d.pool.Query(ctx,
-		"SELECT id, status, accrual, uploaded_at FROM orders "+
-			"WHERE user_id = $1 ORDER BY uploaded_at",
-		userID)
+	query, args := buildListQuery(
+		"SELECT id, status, accrual, uploaded_at FROM orders WHERE user_id = $1",
+		"orders", "uploaded_at", userID, filter)
+
+	rows, err := d.pool.Query(ctx, query, args...)
 	if err != nil {
 		return orders, fmt.Errorf("query error of get orders:%w", err)
 	}
@@ -242,64 +600,747 @@ func (d *db) GetOrders(ctx context.Context, userID int64) ([]ports.Order, error)
 	return orders, nil
 }
 
-func (d *db) GetNotProcessedOrderWithBlock(ctx context.Context, tx pgx.Tx) (int64, error) {
-	var orderID int64
+func (d *db) GetOrder(ctx context.Context, orderID int64) (*ports.Order, error) {
+	log.Ctx(ctx).Debug().Int64("order_id", orderID).Msg("GetOrder")
+	var o ports.Order
+
+	err := d.pool.QueryRow(ctx,
+		"SELECT id, status, accrual, uploaded_at FROM orders WHERE id = $1", orderID).
+		Scan(&o.Number, &o.Status, &o.Accrual, &o.UploadedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ports.ErrOrderNotFound
+	}
 
-	err := tx.QueryRow(ctx, "SELECT id FROM orders WHERE status in ('PROCESSING', 'NEW') "+
-		"LIMIT 1 FOR UPDATE").Scan(&orderID)
 	if err != nil {
-		return 0, fmt.Errorf("query error of get not processed order with block, error:%w", err)
+		return nil, fmt.Errorf("query error of get order:%w", err)
 	}
 
-	return orderID, nil
+	return &o, nil
 }
 
-func (d *db) CreateWithdraw(ctx context.Context, tx pgx.Tx, userID, orderID int64, sum float64) error {
+// RequeueOrder возвращает заказ в статусе INVALID в очередь опроса системы расчёта начислений. Для заказа
+// в другом статусе, как и для несуществующего, возвращает ErrOrderNotFound.
+func (d *db) RequeueOrder(ctx context.Context, orderID int64) error {
+	log.Ctx(ctx).Debug().Int64("order_id", orderID).Msg("RequeueOrder")
 	var id int64
 
-	err := tx.QueryRow(ctx, "INSERT INTO withdrawals (order_id, user_id, sum) VALUES ($1, $2, $3) RETURNING id",
-		orderID, userID, sum).Scan(&id)
+	err := d.pool.QueryRow(ctx,
+		"UPDATE ONLY orders SET status = 'NEW', accrual = NULL WHERE id = $1 AND status = 'INVALID' RETURNING id",
+		orderID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ports.ErrOrderNotFound
+	}
+
 	if err != nil {
-		return fmt.Errorf("query error of create withdraw:%w", err)
+		return fmt.Errorf("query error of requeue order:%w", err)
 	}
 
-	log.Printf("Created withdraw, id:%v", id)
 	return nil
 }
 
-func (d *db) GetWithdrawals(ctx context.Context, userID int64) ([]ports.Withdraw, error) {
-	log.Printf("GetWithdrawals, userID:%v", userID)
-	var withdrawals []ports.Withdraw
+func (d *db) GetOrderPolls(ctx context.Context, orderID int64) ([]ports.OrderPoll, error) {
+	log.Ctx(ctx).Debug().Int64("order_id", orderID).Msg("GetOrderPolls")
+	var polls []ports.OrderPoll
 
 	rows, err := d.pool.Query(ctx,
-		"SELECT order_id, sum, processed_at FROM withdrawals "+
-			"WHERE user_id = $1 ORDER BY processed_at",
-		userID)
+		"SELECT status, accrual, polled_at FROM order_polls WHERE order_id = $1 ORDER BY polled_at, id",
+		orderID)
 	if err != nil {
-		return withdrawals, fmt.Errorf("query error of get withdrawals:%w", err)
+		return polls, fmt.Errorf("query error of get order polls:%w", err)
 	}
 
 	for rows.Next() {
-		var w ports.Withdraw
+		var p ports.OrderPoll
 		err = rows.Scan(
-			&w.Order,
-			&w.Sum,
-			&w.ProcessedAt,
+			&p.Status,
+			&p.Accrual,
+			&p.PolledAt,
 		)
 		if err != nil {
-			return withdrawals, fmt.Errorf("scan error of get withdrawals:%w", err)
+			return polls, fmt.Errorf("scan error of get order polls:%w", err)
 		}
-		withdrawals = append(withdrawals, w)
+		polls = append(polls, p)
 	}
 
 	err = rows.Err()
 	if err != nil {
-		return withdrawals, fmt.Errorf("error of get withdrawals:%w", err)
+		return polls, fmt.Errorf("error of get order polls:%w", err)
 	}
 
-	return withdrawals, nil
+	return polls, nil
 }
 
-func (d *db) Close() {
-	d.pool.Close()
+func (d *db) CreateOrderPoll(ctx context.Context, tx pgx.Tx, orderID int64, status string, accrual float64) error {
+	log.Ctx(ctx).Debug().
+		Int64("order_id", orderID).
+		Str("status", status).
+		Float64("accrual", accrual).
+		Msg("CreateOrderPoll")
+
+	_, err := tx.Exec(ctx,
+		"INSERT INTO order_polls (order_id, status, accrual) VALUES ($1, $2, $3)",
+		orderID, status, accrual)
+	if err != nil {
+		return fmt.Errorf("query error of create order poll:%w", err)
+	}
+
+	return nil
+}
+
+// buildListQuery дополняет запрос выборки списка пользователя условиями фильтра и keyset пагинацией
+// по паре (timeColumn, id). Параметр $1 запроса должен быть идентификатором пользователя.
+func buildListQuery(query, table, timeColumn string, userID int64, filter ports.ListFilter) (string, []any) {
+	args := []any{userID}
+	param := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if len(filter.Statuses) != 0 {
+		query += " AND status::text = ANY(" + param(filter.Statuses) + ")"
+	}
+
+	if !filter.From.IsZero() {
+		query += " AND " + timeColumn + " >= " + param(filter.From)
+	}
+
+	if !filter.To.IsZero() {
+		query += " AND " + timeColumn + " < " + param(filter.To)
+	}
+
+	direction, cmp := "ASC", ">"
+	if filter.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	if filter.After != 0 {
+		query += " AND (" + timeColumn + ", id) " + cmp + " (SELECT " + timeColumn + ", id FROM " + table +
+			" WHERE id = " + param(filter.After) + " AND user_id = $1)"
+	}
+
+	query += " ORDER BY " + timeColumn + " " + direction + ", id " + direction
+
+	if filter.Limit > 0 {
+		query += " LIMIT " + param(filter.Limit)
+	}
+
+	return query, args
+}
+
+// GetNotProcessedOrderWithBlock блокирует до конца транзакции tx один заказ, ожидающий опроса системы
+// расчёта начислений. Заказы, заблокированные параллельными заданиями опроса, пропускаются. Если заказов для
+// опроса нет, возвращает ports.ErrNoOrderToPoll.
+func (d *db) GetNotProcessedOrderWithBlock(ctx context.Context, tx pgx.Tx) (int64, string, error) {
+	var orderID int64
+	var status string
+
+	err := tx.QueryRow(ctx, "SELECT id, status FROM orders WHERE status in ('PROCESSING', 'NEW') "+
+		"LIMIT 1 FOR UPDATE SKIP LOCKED").Scan(&orderID, &status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, "", ports.ErrNoOrderToPoll
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("query error of get not processed order with block, error:%w", err)
+	}
+
+	return orderID, status, nil
+}
+
+// countOrdersBacklog возвращает число заказов, ожидающих опроса системы расчёта начислений, по статусам.
+func (d *db) countOrdersBacklog(ctx context.Context) (map[string]int64, error) {
+	backlog := make(map[string]int64)
+
+	rows, err := d.pool.Query(ctx, "SELECT status, count(*) FROM orders WHERE status in ('PROCESSING', 'NEW') "+
+		"GROUP BY status")
+	if err != nil {
+		return nil, fmt.Errorf("query error of count orders backlog:%w", err)
+	}
+
+	for rows.Next() {
+		var status string
+		var count int64
+		err = rows.Scan(&status, &count)
+		if err != nil {
+			return nil, fmt.Errorf("scan error of count orders backlog:%w", err)
+		}
+		backlog[status] = count
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, fmt.Errorf("error of count orders backlog:%w", err)
+	}
+
+	return backlog, nil
+}
+
+func (d *db) CreateWithdraw(ctx context.Context, tx pgx.Tx, userID, orderID int64, sum float64) error {
+	var id int64
+
+	err := tx.QueryRow(ctx, "INSERT INTO withdrawals (order_id, user_id, sum) VALUES ($1, $2, $3) RETURNING id",
+		orderID, userID, sum).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("query error of create withdraw:%w", err)
+	}
+
+	log.Ctx(ctx).Debug().Int64("id", id).Msg("Created withdraw")
+	return nil
+}
+
+func (d *db) GetWithdrawals(ctx context.Context, userID int64, filter ports.ListFilter) ([]ports.Withdraw, error) {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Interface("filter", filter).Msg("GetWithdrawals")
+	var withdrawals []ports.Withdraw
+
+	filter.Statuses = nil
+	query, args := buildListQuery(
+		"SELECT id, order_id, sum, processed_at FROM withdrawals WHERE user_id = $1",
+		"withdrawals", "processed_at", userID, filter)
+
+	rows, err := d.pool.Query(ctx, query, args...)
+	if err != nil {
+		return withdrawals, fmt.Errorf("query error of get withdrawals:%w", err)
+	}
+
+	for rows.Next() {
+		var w ports.Withdraw
+		err = rows.Scan(
+			&w.ID,
+			&w.Order,
+			&w.Sum,
+			&w.ProcessedAt,
+		)
+		if err != nil {
+			return withdrawals, fmt.Errorf("scan error of get withdrawals:%w", err)
+		}
+		withdrawals = append(withdrawals, w)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return withdrawals, fmt.Errorf("error of get withdrawals:%w", err)
+	}
+
+	return withdrawals, nil
+}
+
+func (d *db) Close() {
+	d.pool.Close()
+}
+
+func (d *db) CreateAPIKey(ctx context.Context, userID int64, name, hash string, scopes []string) (*ports.APIKey,
+	error) {
+	log.Ctx(ctx).Debug().
+		Int64(logging.FieldTargetUserID, userID).
+		Str("name", name).
+		Strs("scopes", scopes).
+		Msg("CreateAPIKey")
+	k := ports.APIKey{
+		UserID: userID,
+		Name:   name,
+		Scopes: scopes,
+	}
+
+	err := d.pool.QueryRow(ctx,
+		"INSERT INTO api_keys (user_id, name, hash, scopes) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		userID, name, hash, scopes).Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("query error of create api key:%w", err)
+	}
+
+	return &k, nil
+}
+
+func (d *db) UseAPIKey(ctx context.Context, hash string) (*ports.APIKey, error) {
+	var k ports.APIKey
+
+	err := d.pool.QueryRow(ctx,
+		"UPDATE ONLY api_keys SET last_used_at = NOW() WHERE hash = $1 AND revoked_at IS NULL "+
+			"AND NOT EXISTS (SELECT 1 FROM users WHERE users.id = api_keys.user_id AND locked_at IS NOT NULL) "+
+			"RETURNING id, user_id, name, scopes, created_at, last_used_at",
+		hash).Scan(&k.ID, &k.UserID, &k.Name, &k.Scopes, &k.CreatedAt, &k.LastUsedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ports.ErrAPIKeyNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("query error of use api key:%w", err)
+	}
+
+	return &k, nil
+}
+
+func (d *db) GetAPIKeys(ctx context.Context, userID int64) ([]ports.APIKey, error) {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("GetAPIKeys")
+	var keys []ports.APIKey
+
+	rows, err := d.pool.Query(ctx,
+		"SELECT id, user_id, name, scopes, created_at, last_used_at, revoked_at FROM api_keys "+
+			"WHERE user_id = $1 ORDER BY id",
+		userID)
+	if err != nil {
+		return keys, fmt.Errorf("query error of get api keys:%w", err)
+	}
+
+	for rows.Next() {
+		var k ports.APIKey
+		err = rows.Scan(
+			&k.ID,
+			&k.UserID,
+			&k.Name,
+			&k.Scopes,
+			&k.CreatedAt,
+			&k.LastUsedAt,
+			&k.RevokedAt,
+		)
+		if err != nil {
+			return keys, fmt.Errorf("scan error of get api keys:%w", err)
+		}
+		keys = append(keys, k)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return keys, fmt.Errorf("error of get api keys:%w", err)
+	}
+
+	return keys, nil
+}
+
+func (d *db) RevokeAPIKey(ctx context.Context, userID, keyID int64) error {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Int64("api_key_id", keyID).Msg("RevokeAPIKey")
+	var id int64
+
+	err := d.pool.QueryRow(ctx,
+		"UPDATE ONLY api_keys SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL "+
+			"RETURNING id",
+		keyID, userID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ports.ErrAPIKeyNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("query error of revoke api key:%w", err)
+	}
+
+	return nil
+}
+
+func (d *db) CreateSession(ctx context.Context, userID int64, userAgent, ip string) (int64, error) {
+	log.Ctx(ctx).Debug().
+		Int64(logging.FieldTargetUserID, userID).
+		Str("user_agent", userAgent).
+		Str("ip", ip).
+		Msg("CreateSession")
+	var id int64
+
+	err := d.pool.QueryRow(ctx,
+		"INSERT INTO sessions (user_id, user_agent, ip) VALUES ($1, $2, $3) RETURNING id",
+		userID, userAgent, ip).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("query error of create session:%w", err)
+	}
+
+	return id, nil
+}
+
+// GetSessionIdle возвращает время, прошедшее с последнего запроса в сессии. Разница считается в БД,
+// так как last_seen_at хранится без часового пояса.
+func (d *db) GetSessionIdle(ctx context.Context, userID, sessionID int64) (time.Duration, error) {
+	var idle float64
+
+	err := d.pool.QueryRow(ctx,
+		"SELECT EXTRACT(EPOCH FROM NOW() - last_seen_at)::double precision FROM sessions "+
+			"WHERE id = $1 AND user_id = $2 AND terminated_at IS NULL",
+		sessionID, userID).Scan(&idle)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ports.ErrSessionNotFound
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("query error of get session idle:%w", err)
+	}
+
+	return time.Duration(idle * float64(time.Second)), nil
+}
+
+func (d *db) TouchSession(ctx context.Context, sessionID int64) error {
+	_, err := d.pool.Exec(ctx, "UPDATE ONLY sessions SET last_seen_at = NOW() WHERE id = $1", sessionID)
+	if err != nil {
+		return fmt.Errorf("query error of touch session:%w", err)
+	}
+
+	return nil
+}
+
+func (d *db) GetSessions(ctx context.Context, userID int64) ([]ports.Session, error) {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("GetSessions")
+	var sessions []ports.Session
+
+	rows, err := d.pool.Query(ctx,
+		"SELECT id, user_agent, ip, created_at, last_seen_at FROM sessions "+
+			"WHERE user_id = $1 AND terminated_at IS NULL ORDER BY last_seen_at DESC",
+		userID)
+	if err != nil {
+		return sessions, fmt.Errorf("query error of get sessions:%w", err)
+	}
+
+	for rows.Next() {
+		var s ports.Session
+		err = rows.Scan(
+			&s.ID,
+			&s.UserAgent,
+			&s.IP,
+			&s.CreatedAt,
+			&s.LastSeenAt,
+		)
+		if err != nil {
+			return sessions, fmt.Errorf("scan error of get sessions:%w", err)
+		}
+		sessions = append(sessions, s)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return sessions, fmt.Errorf("error of get sessions:%w", err)
+	}
+
+	return sessions, nil
+}
+
+func (d *db) TerminateSession(ctx context.Context, userID, sessionID int64) error {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Int64("session_id", sessionID).Msg("TerminateSession")
+	var id int64
+
+	err := d.pool.QueryRow(ctx,
+		"UPDATE ONLY sessions SET terminated_at = NOW() "+
+			"WHERE id = $1 AND user_id = $2 AND terminated_at IS NULL RETURNING id",
+		sessionID, userID).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ports.ErrSessionNotFound
+	}
+
+	if err != nil {
+		return fmt.Errorf("query error of terminate session:%w", err)
+	}
+
+	return nil
+}
+
+// CreateUserEvent сохраняет событие пользователя и отправляет уведомление в канал userEventsChannel.
+// Уведомление доставляется слушателям только после фиксации транзакции tx.
+func (d *db) CreateUserEvent(ctx context.Context, tx pgx.Tx, userID int64, eventType string, data any) error {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Str("event_type", eventType).Msg("CreateUserEvent")
+
+	_, err := tx.Exec(ctx,
+		"INSERT INTO user_events (user_id, type, data) VALUES ($1, $2, $3)",
+		userID, eventType, data)
+	if err != nil {
+		return fmt.Errorf("query error of create user event:%w", err)
+	}
+
+	_, err = tx.Exec(ctx, "SELECT pg_notify($1, $2)", userEventsChannel, strconv.FormatInt(userID, 10))
+	if err != nil {
+		return fmt.Errorf("query error of notify user event:%w", err)
+	}
+
+	return nil
+}
+
+func (d *db) GetUserEvents(ctx context.Context, userID, afterID int64, limit int) ([]ports.UserEvent, error) {
+	var events []ports.UserEvent
+
+	rows, err := d.pool.Query(ctx,
+		"SELECT id, type, data, created_at FROM user_events WHERE user_id = $1 AND id > $2 ORDER BY id LIMIT $3",
+		userID, afterID, limit)
+	if err != nil {
+		return events, fmt.Errorf("query error of get user events:%w", err)
+	}
+
+	for rows.Next() {
+		var e ports.UserEvent
+		err = rows.Scan(
+			&e.ID,
+			&e.Type,
+			&e.Data,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return events, fmt.Errorf("scan error of get user events:%w", err)
+		}
+		events = append(events, e)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return events, fmt.Errorf("error of get user events:%w", err)
+	}
+
+	return events, nil
+}
+
+func (d *db) GetLastUserEventID(ctx context.Context, userID int64) (int64, error) {
+	var id int64
+
+	err := d.pool.QueryRow(ctx,
+		"SELECT COALESCE(MAX(id), 0) FROM user_events WHERE user_id = $1", userID).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("query error of get last user event id:%w", err)
+	}
+
+	return id, nil
+}
+
+// ListenUserEvents подписывается на канал userEventsChannel и вызывает notify с идентификатором
+// пользователя на каждое уведомление. Блокируется до отмены ctx или ошибки соединения.
+func (d *db) ListenUserEvents(ctx context.Context, notify func(userID int64)) error {
+	conn, err := d.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection:%w", err)
+	}
+	// После LISTEN соединение нельзя возвращать в пул: на нём останется подписка.
+	c := conn.Hijack()
+	defer func() {
+		_ = c.Close(context.Background())
+	}()
+
+	_, err = c.Exec(ctx, "LISTEN "+userEventsChannel)
+	if err != nil {
+		return fmt.Errorf("query error of listen user events:%w", err)
+	}
+
+	for {
+		n, err := c.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("error of wait for notification:%w", err)
+		}
+
+		userID, err := strconv.ParseInt(n.Payload, 10, 64)
+		if err != nil {
+			log.Ctx(ctx).Error().Err(err).Str("payload", n.Payload).Msg("invalid user event notification payload")
+			continue
+		}
+
+		notify(userID)
+	}
+}
+
+func (d *db) DeleteWebhooks(ctx context.Context, tx pgx.Tx, userID int64) error {
+	_, err := tx.Exec(ctx, "DELETE FROM webhooks WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("query error of delete webhooks:%w", err)
+	}
+
+	return nil
+}
+
+// CreateWebhookDeliveries добавляет в outbox по доставке события для каждого вебхука пользователя,
+// подписанного на eventType.
+func (d *db) CreateWebhookDeliveries(ctx context.Context, tx pgx.Tx, userID int64, eventType string,
+	payload any) error {
+	log.Ctx(ctx).Debug().
+		Int64(logging.FieldTargetUserID, userID).
+		Str("event_type", eventType).
+		Msg("CreateWebhookDeliveries")
+
+	_, err := tx.Exec(ctx,
+		"INSERT INTO webhook_deliveries (webhook_id, event_type, payload) "+
+			"SELECT id, $2, $3 FROM webhooks WHERE user_id = $1 AND $2 = ANY(events)",
+		userID, eventType, payload)
+	if err != nil {
+		return fmt.Errorf("query error of create webhook deliveries:%w", err)
+	}
+
+	return nil
+}
+
+func (d *db) CreateWebhook(ctx context.Context, userID int64, url, secret string, events []string) (*ports.Webhook,
+	error) {
+	log.Ctx(ctx).Debug().
+		Int64(logging.FieldTargetUserID, userID).
+		Str("url", url).
+		Strs("events", events).
+		Msg("CreateWebhook")
+	w := ports.Webhook{
+		UserID: userID,
+		URL:    url,
+		Secret: secret,
+		Events: events,
+	}
+
+	err := d.pool.QueryRow(ctx,
+		"INSERT INTO webhooks (user_id, url, secret, events) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		userID, url, secret, events).Scan(&w.ID, &w.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("query error of create webhook:%w", err)
+	}
+
+	return &w, nil
+}
+
+func (d *db) GetWebhooks(ctx context.Context, userID int64) ([]ports.Webhook, error) {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Msg("GetWebhooks")
+	var webhooks []ports.Webhook
+
+	rows, err := d.pool.Query(ctx,
+		"SELECT id, user_id, url, events, created_at FROM webhooks WHERE user_id = $1 ORDER BY id",
+		userID)
+	if err != nil {
+		return webhooks, fmt.Errorf("query error of get webhooks:%w", err)
+	}
+
+	for rows.Next() {
+		var w ports.Webhook
+		err = rows.Scan(
+			&w.ID,
+			&w.UserID,
+			&w.URL,
+			&w.Events,
+			&w.CreatedAt,
+		)
+		if err != nil {
+			return webhooks, fmt.Errorf("scan error of get webhooks:%w", err)
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return webhooks, fmt.Errorf("error of get webhooks:%w", err)
+	}
+
+	return webhooks, nil
+}
+
+func (d *db) DeleteWebhook(ctx context.Context, userID, webhookID int64) error {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Int64("webhook_id", webhookID).Msg("DeleteWebhook")
+
+	tag, err := d.pool.Exec(ctx, "DELETE FROM webhooks WHERE id = $1 AND user_id = $2", webhookID, userID)
+	if err != nil {
+		return fmt.Errorf("query error of delete webhook:%w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ports.ErrWebhookNotFound
+	}
+
+	return nil
+}
+
+func (d *db) GetWebhookDeliveries(ctx context.Context, userID, webhookID int64, limit int) ([]ports.WebhookDelivery,
+	error) {
+	log.Ctx(ctx).Debug().Int64(logging.FieldTargetUserID, userID).Int64("webhook_id", webhookID).
+		Msg("GetWebhookDeliveries")
+	var deliveries []ports.WebhookDelivery
+
+	var id int64
+	err := d.pool.QueryRow(ctx, "SELECT id FROM webhooks WHERE id = $1 AND user_id = $2", webhookID, userID).
+		Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return deliveries, ports.ErrWebhookNotFound
+	}
+
+	if err != nil {
+		return deliveries, fmt.Errorf("query error of get webhook:%w", err)
+	}
+
+	rows, err := d.pool.Query(ctx,
+		"SELECT id, webhook_id, event_type, payload, status, attempts, next_attempt_at, last_attempt_at, "+
+			"last_status_code, last_error, created_at, delivered_at FROM webhook_deliveries "+
+			"WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2",
+		webhookID, limit)
+	if err != nil {
+		return deliveries, fmt.Errorf("query error of get webhook deliveries:%w", err)
+	}
+
+	for rows.Next() {
+		var w ports.WebhookDelivery
+		err = rows.Scan(
+			&w.ID,
+			&w.WebhookID,
+			&w.EventType,
+			&w.Payload,
+			&w.Status,
+			&w.Attempts,
+			&w.NextAttemptAt,
+			&w.LastAttemptAt,
+			&w.LastStatusCode,
+			&w.LastError,
+			&w.CreatedAt,
+			&w.DeliveredAt,
+		)
+		if err != nil {
+			return deliveries, fmt.Errorf("scan error of get webhook deliveries:%w", err)
+		}
+		deliveries = append(deliveries, w)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return deliveries, fmt.Errorf("error of get webhook deliveries:%w", err)
+	}
+
+	return deliveries, nil
+}
+
+// ClaimWebhookDeliveries захватывает до limit доставок, время отправки которых наступило, сдвигая следующую
+// попытку на lease. Если отправитель упадёт, не сохранив результат, доставка будет повторена после lease.
+func (d *db) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]ports.WebhookDelivery,
+	error) {
+	var deliveries []ports.WebhookDelivery
+
+	rows, err := d.pool.Query(ctx,
+		"WITH due AS (SELECT id FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= NOW() "+
+			"ORDER BY next_attempt_at LIMIT $1 FOR UPDATE SKIP LOCKED) "+
+			"UPDATE webhook_deliveries d SET next_attempt_at = NOW() + make_interval(secs => $2) "+
+			"FROM due, webhooks w WHERE d.id = due.id AND w.id = d.webhook_id "+
+			"RETURNING d.id, d.webhook_id, d.event_type, d.payload, d.attempts, d.created_at, w.url, w.secret",
+		limit, lease.Seconds())
+	if err != nil {
+		return deliveries, fmt.Errorf("query error of claim webhook deliveries:%w", err)
+	}
+
+	for rows.Next() {
+		var w ports.WebhookDelivery
+		err = rows.Scan(
+			&w.ID,
+			&w.WebhookID,
+			&w.EventType,
+			&w.Payload,
+			&w.Attempts,
+			&w.CreatedAt,
+			&w.URL,
+			&w.Secret,
+		)
+		if err != nil {
+			return deliveries, fmt.Errorf("scan error of claim webhook deliveries:%w", err)
+		}
+		deliveries = append(deliveries, w)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return deliveries, fmt.Errorf("error of claim webhook deliveries:%w", err)
+	}
+
+	return deliveries, nil
+}
+
+func (d *db) SaveWebhookDeliveryAttempt(ctx context.Context, a *ports.WebhookDeliveryAttempt) error {
+	log.Ctx(ctx).Debug().
+		Int64("delivery_id", a.ID).
+		Str("status", a.Status).
+		Int("status_code", a.StatusCode).
+		Msg("SaveWebhookDeliveryAttempt")
+
+	_, err := d.pool.Exec(ctx,
+		"UPDATE ONLY webhook_deliveries SET status = $2, attempts = attempts + 1, last_attempt_at = NOW(), "+
+			"last_status_code = NULLIF($3, 0), last_error = NULLIF($4, ''), "+
+			"next_attempt_at = NOW() + make_interval(secs => $5), "+
+			"delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() END WHERE id = $1",
+		a.ID, a.Status, a.StatusCode, a.Error, a.RetryIn.Seconds())
+	if err != nil {
+		return fmt.Errorf("query error of save webhook delivery attempt:%w", err)
+	}
+
+	return nil
 }
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildListQuery(t *testing.T) {
+	const base = "SELECT id FROM orders WHERE user_id = $1"
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		filter ports.ListFilter
+		query  string
+		args   []any
+	}{
+		{
+			name:   "Without filter the whole list is returned",
+			filter: ports.ListFilter{},
+			query:  base + " ORDER BY uploaded_at ASC, id ASC",
+			args:   []any{int64(1)},
+		},
+		{
+			name:   "Limit",
+			filter: ports.ListFilter{Limit: 10},
+			query:  base + " ORDER BY uploaded_at ASC, id ASC LIMIT $2",
+			args:   []any{int64(1), 10},
+		},
+		{
+			name:   "Cursor ascending",
+			filter: ports.ListFilter{After: 7, Limit: 10},
+			query: base + " AND (uploaded_at, id) > (SELECT uploaded_at, id FROM orders WHERE id = $2 AND user_id = $1)" +
+				" ORDER BY uploaded_at ASC, id ASC LIMIT $3",
+			args: []any{int64(1), int64(7), 10},
+		},
+		{
+			name:   "Cursor descending",
+			filter: ports.ListFilter{After: 7, Desc: true},
+			query: base + " AND (uploaded_at, id) < (SELECT uploaded_at, id FROM orders WHERE id = $2 AND user_id = $1)" +
+				" ORDER BY uploaded_at DESC, id DESC",
+			args: []any{int64(1), int64(7)},
+		},
+		{
+			name:   "Statuses and time range",
+			filter: ports.ListFilter{Statuses: []string{"NEW", "INVALID"}, From: from, To: to, Limit: 5},
+			query: base + " AND status::text = ANY($2) AND uploaded_at >= $3 AND uploaded_at < $4" +
+				" ORDER BY uploaded_at ASC, id ASC LIMIT $5",
+			args: []any{int64(1), []string{"NEW", "INVALID"}, from, to, 5},
+		},
+		{
+			name:   "Only from",
+			filter: ports.ListFilter{From: from},
+			query:  base + " AND uploaded_at >= $2 ORDER BY uploaded_at ASC, id ASC",
+			args:   []any{int64(1), from},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			query, args := buildListQuery(base, "orders", "uploaded_at", 1, test.filter)
+			assert.Equal(t, test.query, query)
+			assert.Equal(t, test.args, args)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/k0st1a/gophermart/internal/adapters/cli"
+	"github.com/k0st1a/gophermart/internal/adapters/db"
+	"github.com/k0st1a/gophermart/internal/pkg/admin"
+	"github.com/k0st1a/gophermart/internal/pkg/auth"
+	"github.com/k0st1a/gophermart/internal/pkg/cfg"
+	"github.com/k0st1a/gophermart/internal/pkg/logging"
+	"github.com/k0st1a/gophermart/internal/pkg/order"
+	"github.com/k0st1a/gophermart/internal/pkg/user"
+	"github.com/k0st1a/gophermart/internal/pkg/withdraw"
+)
+
+// ctlMaxConns число соединений с БД у gophermartctl: команда выполняет запросы последовательно.
+const ctlMaxConns = 2
+
+// Ctl выполняет команду поддержки gophermartctl, заданную аргументами командной строки args после флагов.
+// Результат пишется в stdout, лог - в stderr.
+func Ctl(args []string) error {
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancelCtx()
+
+	cfg, args, err := cfg.NewTool(args)
+	if err != nil {
+		return fmt.Errorf("config error:%w", err)
+	}
+
+	err = logging.Setup(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		return fmt.Errorf("failed to setup logging:%w", err)
+	}
+
+	db, err := db.NewDB(ctx, cfg.DatabaseURI, db.Options{
+		MaxConns: ctlMaxConns,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create db:%w", err)
+	}
+	defer db.Close()
+
+	h := cli.NewHandler(os.Stdout,
+		auth.New(cfg.SecretKey, cfg.TokenTTL, cfg.BcryptCost),
+		user.New(db),
+		order.New(db),
+		withdraw.New(db),
+		admin.New(db),
+	)
+
+	//nolint:wrapcheck //errors of commands are already wrapped
+	return h.Run(ctx, args)
+}
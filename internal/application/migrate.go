@@ -0,0 +1,92 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/k0st1a/gophermart/internal/adapters/db"
+	"github.com/k0st1a/gophermart/internal/pkg/cfg"
+	"github.com/k0st1a/gophermart/internal/pkg/logging"
+	"github.com/rs/zerolog/log"
+)
+
+// migrateUsage аргументы подкоманды migrate после флагов.
+const migrateUsage = "up|down [N]|version|force VERSION"
+
+// Migrate выполняет действие с миграциями БД, заданное аргументами командной строки args после флагов.
+func Migrate(args []string) error {
+	cfg, args, err := cfg.NewTool(args)
+	if err != nil {
+		return fmt.Errorf("config error:%w", err)
+	}
+
+	err = logging.Setup(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		return fmt.Errorf("failed to setup logging:%w", err)
+	}
+
+	if len(args) == 0 {
+		return errors.New("migrate action is required, usage: " + migrateUsage)
+	}
+	action, args := args[0], args[1:]
+
+	dsn := cfg.MigrationDatabaseURI
+	if dsn == "" {
+		dsn = cfg.DatabaseURI
+	}
+
+	m, err := db.NewMigrator(context.Background(), dsn)
+	if err != nil {
+		return fmt.Errorf("failed to create migrator:%w", err)
+	}
+	defer func() {
+		err := m.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("error of close migrator")
+		}
+	}()
+
+	switch {
+	case action == "up" && len(args) == 0:
+		err = m.Up()
+	case action == "down" && len(args) <= 1:
+		steps := 1
+		if len(args) == 1 {
+			steps, err = strconv.Atoi(args[0])
+			if err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of migrations:%q", args[0])
+			}
+		}
+		err = m.Down(steps)
+	case action == "version" && len(args) == 0:
+		var version uint
+		var dirty bool
+		version, dirty, err = m.Version()
+		if err == nil {
+			fmt.Printf("version:%d dirty:%t\n", version, dirty)
+		}
+		return err
+	case action == "force" && len(args) == 1:
+		var version int
+		version, err = strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid migration version:%q", args[0])
+		}
+		err = m.Force(version)
+	default:
+		return errors.New("invalid migrate arguments, usage: " + migrateUsage)
+	}
+	if err != nil {
+		return err
+	}
+
+	version, dirty, err := m.Version()
+	if err != nil {
+		return err
+	}
+	log.Info().Str("action", action).Uint("version", version).Bool("dirty", dirty).Msg("migrations done")
+
+	return nil
+}
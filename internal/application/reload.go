@@ -0,0 +1,101 @@
+package application
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/k0st1a/gophermart/internal/pkg/cfg"
+	"github.com/k0st1a/gophermart/internal/pkg/cron"
+	"github.com/k0st1a/gophermart/internal/pkg/logging"
+	"github.com/rs/zerolog/log"
+)
+
+// reloader перечитывает конфигурацию по SIGHUP и применяет перезагружаемые настройки к работающему
+// сервису: уровень логирования, настройки цикла опроса и ключи проверки подписи токенов.
+type reloader struct {
+	current *cfg.Config
+	// ticker цикл опроса системы расчёта начислений, nil - процесс не опрашивает систему расчёта начислений.
+	ticker interface {
+		Update(s cron.Settings)
+	}
+	auth interface {
+		SetVerificationKeys(keys []string)
+	}
+}
+
+// Run обрабатывает SIGHUP до отмены контекста ctx.
+func (r *reloader) Run(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			r.reload()
+		}
+	}
+}
+
+// reload перечитывает конфигурацию. Если новая конфигурация или её объединение с текущей неверны, сервис
+// продолжает работать с текущей.
+func (r *reloader) reload() {
+	log.Info().Msg("reloading config")
+
+	next, err := cfg.Reload()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to reload config, keeping current config")
+		return
+	}
+
+	changes := r.current.Changes(next)
+	if len(changes) == 0 {
+		log.Info().Msg("config reloaded, no changes")
+		return
+	}
+
+	for _, c := range changes {
+		if c.Reloadable {
+			log.Info().Str("key", c.Key).Str("old", c.Old).Str("new", c.New).Msg("config setting changed")
+		} else {
+			log.Warn().Str("key", c.Key).Str("old", c.Old).Str("new", c.New).
+				Msg("config setting changed, restart required to apply")
+		}
+	}
+
+	merged, err := r.current.Merge(next)
+	if err != nil {
+		log.Error().Err(err).Msg("merged config is invalid, keeping current config")
+		return
+	}
+
+	r.current = merged
+	r.apply()
+}
+
+// apply применяет перезагружаемые настройки текущей конфигурации.
+func (r *reloader) apply() {
+	err := logging.SetLevel(r.current.LogLevel)
+	if err != nil {
+		log.Error().Err(err).Msg("error of set log level")
+	}
+
+	if r.ticker != nil {
+		r.ticker.Update(pollSettings(r.current))
+	}
+	r.auth.SetVerificationKeys(r.current.JWTVerificationKeys)
+}
+
+// pollSettings возвращает настройки цикла опроса системы расчёта начислений из конфигурации c.
+func pollSettings(c *cfg.Config) cron.Settings {
+	return cron.Settings{
+		AccrualAddress: c.AccrualSystemAddress,
+		Interval:       c.PollInterval,
+		AccrualTimeout: c.AccrualTimeout,
+		Concurrency:    c.PollConcurrency,
+	}
+}
@@ -8,75 +8,348 @@ import (
 	"os"
 	"os/signal"
 	"sync"
+	"syscall"
 
 	"github.com/k0st1a/gophermart/internal/adapters/api/rest"
+	"github.com/k0st1a/gophermart/internal/adapters/api/rpc"
 	"github.com/k0st1a/gophermart/internal/adapters/db"
+	"github.com/k0st1a/gophermart/internal/pkg/admin"
+	"github.com/k0st1a/gophermart/internal/pkg/apikey"
 	"github.com/k0st1a/gophermart/internal/pkg/auth"
 	"github.com/k0st1a/gophermart/internal/pkg/cfg"
 	"github.com/k0st1a/gophermart/internal/pkg/cron"
+	"github.com/k0st1a/gophermart/internal/pkg/events"
+	"github.com/k0st1a/gophermart/internal/pkg/export"
+	"github.com/k0st1a/gophermart/internal/pkg/health"
+	"github.com/k0st1a/gophermart/internal/pkg/logging"
+	"github.com/k0st1a/gophermart/internal/pkg/metrics"
 	"github.com/k0st1a/gophermart/internal/pkg/order"
+	"github.com/k0st1a/gophermart/internal/pkg/session"
+	"github.com/k0st1a/gophermart/internal/pkg/sso"
+	"github.com/k0st1a/gophermart/internal/pkg/tracing"
 	"github.com/k0st1a/gophermart/internal/pkg/user"
+	"github.com/k0st1a/gophermart/internal/pkg/webhook"
 	"github.com/k0st1a/gophermart/internal/pkg/withdraw"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog/log"
 )
 
-func Run() error {
-	log.Printf("Running application")
-	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt)
+// Mode набор компонентов, запускаемых процессом.
+type Mode int
+
+const (
+	// ModeAll запускает API и фоновые задачи в одном процессе.
+	ModeAll Mode = iota
+	// ModeServe запускает HTTP и gRPC API и рассылку событий пользователям. Опрос системы расчёта начислений
+	// и доставка вебхуков не запускаются, поэтому число реплик API можно менять независимо от них.
+	ModeServe
+	// ModeWorker запускает опрос системы расчёта начислений и доставку вебхуков. HTTP сервер отдаёт только
+	// /healthz и /readyz.
+	ModeWorker
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeServe:
+		return "serve"
+	case ModeWorker:
+		return "worker"
+	default:
+		return "all"
+	}
+}
+
+// api сообщает, запускает ли процесс HTTP и gRPC API.
+func (m Mode) api() bool {
+	return m != ModeWorker
+}
+
+// worker сообщает, запускает ли процесс фоновые задачи: опрос системы расчёта начислений и доставку вебхуков.
+func (m Mode) worker() bool {
+	return m != ModeServe
+}
+
+// poller цикл опроса системы расчёта начислений, см. cron.NewTicker.
+type poller interface {
+	Run(ctx context.Context) error
+	Stop()
+	Update(s cron.Settings)
+	Check(ctx context.Context) error
+}
+
+// Run запускает компоненты mode с конфигурацией из аргументов командной строки args и работает до сигнала
+// SIGINT или SIGTERM.
+func Run(mode Mode, args []string) error {
+	log.Info().Stringer("mode", mode).Msg("running application")
+	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancelCtx()
 
-	cfg, err := cfg.New()
+	cfg, err := cfg.New(args)
 	if err != nil {
 		return fmt.Errorf("config error:%w", err)
 	}
 
+	err = logging.Setup(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		return fmt.Errorf("failed to setup logging:%w", err)
+	}
+
 	cfg.Print()
 
-	db, err := db.NewDB(ctx, cfg.DatabaseURI)
+	shutdownTracing, err := tracing.Setup(ctx, cfg.TracesExporter)
+	if err != nil {
+		return fmt.Errorf("failed to setup tracing:%w", err)
+	}
+	defer func() {
+		err := shutdownTracing(context.Background())
+		if err != nil {
+			log.Error().Err(err).Msg("error of shutdown tracing")
+		}
+	}()
+
+	db, err := db.NewDB(ctx, cfg.DatabaseURI, db.Options{
+		MaxConns: cfg.DBMaxConns,
+		MinConns: cfg.DBMinConns,
+		// Миграции применяет процесс API или отдельный запуск gophermart migrate.
+		AutoMigrate:  cfg.AutoMigrate && mode.api(),
+		MigrationDSN: cfg.MigrationDatabaseURI,
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create db:%w", err)
 	}
 	defer db.Close()
 
-	auth := auth.New(cfg.SecretKey)
+	auth := auth.New(cfg.SecretKey, cfg.TokenTTL, cfg.BcryptCost)
+	auth.SetVerificationKeys(cfg.JWTVerificationKeys)
 	user := user.New(db)
 	order := order.New(db)
 	withdraw := withdraw.New(db)
+	admin := admin.New(db)
+	apikey := apikey.New(db)
+
+	var provider sso.Provider
+	if cfg.OIDCIssuer != "" {
+		provider, err = sso.New(ctx, sso.Config{
+			Issuer:       cfg.OIDCIssuer,
+			ClientID:     cfg.OIDCClientID,
+			ClientSecret: cfg.OIDCClientSecret,
+			RedirectURL:  cfg.OIDCRedirectURL,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create oidc provider:%w", err)
+		}
+	}
 
-	h := rest.NewHandler(auth, user, order, withdraw)
-	r := rest.BuildRouter(h, auth)
+	export := export.New(db, order, withdraw)
+
+	session := session.New(db)
+
+	events := events.New(db)
+	dispatcher := webhook.NewDispatcher(db)
+	webhook := webhook.New(db)
+
+	h := rest.NewHandler(auth, user, order, withdraw, admin, apikey, provider, export, session, events, webhook)
+	registry := prometheus.NewRegistry()
+	err = metrics.Register(registry, db.Collector())
+	if err != nil {
+		return fmt.Errorf("failed to register metrics:%w", err)
+	}
 
-	server := rest.New(ctx, cfg.RunAddress, r)
+	readiness := health.New()
+	readiness.Add("database", db.Ping)
+	readiness.Add("migrations", db.CheckMigrations)
+
+	reloader := &reloader{current: cfg, auth: auth}
+
+	var t poller
+	if mode.worker() {
+		t = cron.NewTicker(pollSettings(cfg), db)
+		readiness.Add("accrual_poller", t.Check)
+		reloader.ticker = t
+	}
+
+	routerOpts := rest.RouterOptions{
+		MaxBodySize: cfg.MaxBodySize,
+		Health:      readiness,
+		DevMode:     cfg.DevMode,
+	}
+	var r http.Handler
+	if mode.api() {
+		r, err = rest.BuildRouter(h, auth, apikey, session, routerOpts)
+		if err != nil {
+			return fmt.Errorf("failed to build router:%w", err)
+		}
+	} else {
+		r = rest.BuildProbeRouter(routerOpts)
+	}
+
+	// Ошибки компонентов: любая из них запускает остановку сервиса, а Run возвращает их все.
+	var (
+		errsMu sync.Mutex
+		errs   []error
+	)
+	fail := func(err error) {
+		errsMu.Lock()
+		defer errsMu.Unlock()
+		errs = append(errs, err)
+		cancelCtx()
+	}
+
+	serverOpts := rest.ServerOptions{
+		ReadHeaderTimeout: cfg.HTTPReadHeaderTimeout,
+		IdleTimeout:       cfg.HTTPIdleTimeout,
+	}
+	server := rest.New(ctx, cfg.RunAddress, r, serverOpts)
 
 	go func() {
 		err := server.Run()
 		if errors.Is(err, http.ErrServerClosed) {
-			log.Printf("rest server closed")
+			log.Info().Msg("rest server closed")
 			return
 		}
 		if err != nil {
 			log.Error().Err(err).Msg("failed to run server")
+			fail(fmt.Errorf("rest server:%w", err))
 		}
 	}()
 
-	t := cron.NewTicker(cfg.AccrualSystemAddress, 1, db)
+	// Метрики отдаются только на отдельном внутреннем адресе, см. rest.BuildMetricsRouter.
+	var metricsServer interface {
+		Shutdown(ctx context.Context) error
+	}
+	if cfg.MetricsAddress != "" {
+		ms := rest.New(ctx, cfg.MetricsAddress,
+			rest.BuildMetricsRouter(promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})),
+			serverOpts)
+		metricsServer = ms
+
+		go func() {
+			err := ms.Run()
+			if errors.Is(err, http.ErrServerClosed) {
+				log.Info().Msg("metrics server closed")
+				return
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("failed to run metrics server")
+				fail(fmt.Errorf("metrics server:%w", err))
+			}
+		}()
+	}
+
+	var grpcServer interface {
+		Shutdown(ctx context.Context) error
+	}
+	if mode.api() && cfg.GRPCAddress != "" {
+		gs := rpc.New(cfg.GRPCAddress, rpc.NewHandler(auth, user, order, withdraw, session), auth, user, session)
+		grpcServer = gs
+
+		go func() {
+			err := gs.Run()
+			if err != nil {
+				log.Error().Err(err).Msg("failed to run grpc server")
+				fail(fmt.Errorf("grpc server:%w", err))
+			}
+		}()
+	}
+
+	// Фоновые задачи останавливаются после HTTP и gRPC серверов, чтобы обрабатываемые запросы могли
+	// дождаться событий и доставок. Задания опроса прерываются (отменой jobsCtx), только если не успели
+	// завершиться за время остановки.
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	jobsCtx, abortJobs := context.WithCancel(context.Background())
+	defer abortJobs()
+
 	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		err := t.Run(ctx)
-		if err != nil {
-			log.Error().Err(err).Msg("error of run ticker")
-		}
-	}()
+	if mode.api() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := events.Run(workersCtx)
+			if err != nil {
+				log.Error().Err(err).Msg("error of run events broker")
+				fail(fmt.Errorf("events broker:%w", err))
+			}
+		}()
+	}
+
+	if mode.worker() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := dispatcher.Run(workersCtx)
+			if err != nil {
+				log.Error().Err(err).Msg("error of run webhook dispatcher")
+				fail(fmt.Errorf("webhook dispatcher:%w", err))
+			}
+		}()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := t.Run(jobsCtx)
+			if err != nil {
+				log.Error().Err(err).Msg("error of run ticker")
+				fail(fmt.Errorf("ticker:%w", err))
+			}
+		}()
+	}
+
+	go reloader.Run(ctx)
 
 	<-ctx.Done()
-	wg.Wait()
+	log.Info().Dur("timeout", cfg.ShutdownTimeout).Msg("shutting down")
+	readiness.Shutdown()
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelShutdown()
 
-	err = server.Shutdown(context.Background())
+	err = server.Shutdown(shutdownCtx)
 	if err != nil {
 		log.Error().Err(err).Msg("error of shutdown server")
+		fail(fmt.Errorf("rest server:%w", err))
+	}
+
+	if metricsServer != nil {
+		err = metricsServer.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Error().Err(err).Msg("error of shutdown metrics server")
+			fail(fmt.Errorf("metrics server:%w", err))
+		}
 	}
 
-	return nil
+	if grpcServer != nil {
+		err = grpcServer.Shutdown(shutdownCtx)
+		if err != nil {
+			log.Error().Err(err).Msg("error of shutdown grpc server")
+			fail(fmt.Errorf("grpc server:%w", err))
+		}
+	}
+
+	if t != nil {
+		t.Stop()
+	}
+	stopWorkers()
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-shutdownCtx.Done():
+		log.Error().Msg("accrual jobs did not finish in time, aborting")
+		fail(fmt.Errorf("accrual jobs did not finish in time:%w", shutdownCtx.Err()))
+		abortJobs()
+		<-done
+	}
+
+	log.Info().Msg("application stopped")
+
+	errsMu.Lock()
+	defer errsMu.Unlock()
+	return errors.Join(errs...)
 }
@@ -0,0 +1,128 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/pkg/events"
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+type Managment interface {
+	GetUser(ctx context.Context, login string) (*User, error)
+	AdjustBalance(ctx context.Context, adminID, userID int64, amount float64, reason string) error
+}
+
+type User struct {
+	// LockedAt время блокировки пользователя, нулевое - пользователь не заблокирован.
+	LockedAt  time.Time
+	Login     string
+	Role      string
+	ID        int64
+	Balance   float64
+	Withdrawn float64
+}
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrEmptyReason     = errors.New("reason of balance adjustment is empty")
+	ErrZeroAmount      = errors.New("amount of balance adjustment is zero")
+	ErrNotFiniteAmount = errors.New("amount of balance adjustment is not finite")
+	ErrNegativeBalance = errors.New("balance adjustment leads to negative balance")
+)
+
+type admin struct {
+	storage ports.AdminStorage
+}
+
+func New(storage ports.AdminStorage) Managment {
+	return &admin{
+		storage: storage,
+	}
+}
+
+func (a *admin) GetUser(ctx context.Context, login string) (*User, error) {
+	u, err := a.storage.GetUserByLogin(ctx, login)
+	if err != nil {
+		if errors.Is(err, ports.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+
+		return nil, fmt.Errorf("storage error of get user by login:%w", err)
+	}
+
+	return &User{
+		ID:        u.ID,
+		Login:     u.Login,
+		Role:      u.Role,
+		Balance:   u.Balance,
+		Withdrawn: u.Withdrawn,
+		LockedAt:  u.LockedAt.Time,
+	}, nil
+}
+
+// AdjustBalance начисляет (amount > 0) или списывает (amount < 0) баллы пользователю.
+// Причина корректировки обязательна и сохраняется вместе с идентификатором администратора.
+func (a *admin) AdjustBalance(ctx context.Context, adminID, userID int64, amount float64, reason string) error {
+	log.Ctx(ctx).Info().
+		Int64("target_user_id", userID).
+		Float64("amount", amount).
+		Str("reason", reason).
+		Msg("adjust balance")
+
+	if strings.TrimSpace(reason) == "" {
+		return ErrEmptyReason
+	}
+
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return ErrNotFiniteAmount
+	}
+
+	if amount == 0 {
+		return ErrZeroAmount
+	}
+
+	tx, err := a.storage.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("storage error of begin transaction:%w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	balance, err := a.storage.GetBalanceWithBlock(ctx, tx, userID)
+	if err != nil {
+		return fmt.Errorf("storage error of get balance with block:%w", err)
+	}
+
+	if balance+amount < 0 {
+		return ErrNegativeBalance
+	}
+
+	err = a.storage.UpdateBalance(ctx, tx, userID, balance+amount)
+	if err != nil {
+		return fmt.Errorf("storage error of update balance:%w", err)
+	}
+
+	err = a.storage.CreateBalanceAdjustment(ctx, tx, userID, adminID, amount, reason)
+	if err != nil {
+		return fmt.Errorf("storage error of create balance adjustment:%w", err)
+	}
+
+	err = a.storage.CreateUserEvent(ctx, tx, userID, events.TypeBalanceUpdated, events.Balance{Current: balance + amount})
+	if err != nil {
+		return fmt.Errorf("storage error of create balance event:%w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage error of commit transaction:%w", err)
+	}
+
+	return nil
+}
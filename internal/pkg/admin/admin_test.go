@@ -0,0 +1,95 @@
+package admin
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubTx struct {
+	pgx.Tx
+	committed bool
+}
+
+func (t *stubTx) Commit(_ context.Context) error {
+	t.committed = true
+	return nil
+}
+
+func (t *stubTx) Rollback(_ context.Context) error {
+	return nil
+}
+
+type stubStorage struct {
+	ports.AdminStorage
+	tx          *stubTx
+	balance     float64
+	adjustments []float64
+}
+
+func (s *stubStorage) BeginTx(_ context.Context) (pgx.Tx, error) {
+	return s.tx, nil
+}
+
+func (s *stubStorage) GetBalanceWithBlock(_ context.Context, _ pgx.Tx, _ int64) (float64, error) {
+	return s.balance, nil
+}
+
+func (s *stubStorage) UpdateBalance(_ context.Context, _ pgx.Tx, _ int64, balance float64) error {
+	s.balance = balance
+	return nil
+}
+
+func (s *stubStorage) CreateBalanceAdjustment(_ context.Context, _ pgx.Tx, _, _ int64, amount float64,
+	_ string) error {
+	s.adjustments = append(s.adjustments, amount)
+	return nil
+}
+
+func (s *stubStorage) CreateUserEvent(_ context.Context, _ pgx.Tx, _ int64, _ string, _ any) error {
+	return nil
+}
+
+func TestAdjustBalance(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  float64
+		reason  string
+		balance float64
+		err     error
+	}{
+		{name: "Accrue", amount: 50, reason: "compensation", balance: 150},
+		{name: "Write off", amount: -100, reason: "fraud", balance: 0},
+		{name: "Empty reason", amount: 50, reason: "  ", balance: 100, err: ErrEmptyReason},
+		{name: "Zero amount", reason: "nothing", balance: 100, err: ErrZeroAmount},
+		{name: "NaN amount", amount: math.NaN(), reason: "broken", balance: 100, err: ErrNotFiniteAmount},
+		{name: "Inf amount", amount: math.Inf(1), reason: "broken", balance: 100, err: ErrNotFiniteAmount},
+		{name: "Negative Inf amount", amount: math.Inf(-1), reason: "broken", balance: 100, err: ErrNotFiniteAmount},
+		{name: "Negative balance", amount: -101, reason: "fraud", balance: 100, err: ErrNegativeBalance},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &stubStorage{tx: &stubTx{}, balance: 100}
+			a := New(s)
+
+			err := a.AdjustBalance(context.Background(), 1, 2, test.amount, test.reason)
+			assert.Equal(t, test.balance, s.balance)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				assert.Empty(t, s.adjustments)
+				assert.False(t, s.tx.committed)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []float64{test.amount}, s.adjustments)
+			assert.True(t, s.tx.committed)
+		})
+	}
+}
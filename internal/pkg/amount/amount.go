@@ -0,0 +1,57 @@
+// Package amount разбирает и форматирует суммы баллов в сотых долях балла, чтобы входные суммы всех транспортов
+// (REST API v2, gRPC, gophermartctl) проверялись одинаково и не теряли точность.
+package amount
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// scale число знаков после запятой.
+const scale = 2
+
+var (
+	ErrInvalid = errors.New("amount must be a non-negative decimal string with at most two fraction digits")
+
+	pattern = regexp.MustCompile(`^[0-9]+(\.[0-9]{1,2})?$`)
+)
+
+// Parse разбирает неотрицательную десятичную строку не более чем с двумя знаками после запятой ("500",
+// "500.5", "500.50") в сотые доли балла без округления. Экспонента, знак, NaN и Inf не принимаются.
+func Parse(s string) (int64, error) {
+	if !pattern.MatchString(s) {
+		return 0, ErrInvalid
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	v, err := strconv.ParseInt(whole+frac+strings.Repeat("0", scale-len(frac)), 10, 64)
+	if err != nil {
+		return 0, ErrInvalid
+	}
+
+	return v, nil
+}
+
+// Format возвращает сумму в сотых долях балла строкой с двумя знаками после запятой, например "-0.05".
+func Format(v int64) string {
+	sign, u := "", uint64(v)
+	if v < 0 {
+		sign, u = "-", -u
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, u/100, u%100)
+}
+
+// FromFloat округляет сумму сервисного слоя до сотых.
+func FromFloat(v float64) int64 {
+	return int64(math.Round(v * 100))
+}
+
+// ToFloat возвращает сумму в баллах для сервисного слоя.
+func ToFloat(v int64) float64 {
+	return float64(v) / 100
+}
@@ -0,0 +1,51 @@
+package amount
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{name: "Two fraction digits", in: "751.25", want: 75125},
+		{name: "One fraction digit", in: "0.1", want: 10},
+		{name: "Integer", in: "100", want: 10000},
+		{name: "Zero", in: "0", want: 0},
+		{name: "Negative", in: "-100", wantErr: true},
+		{name: "Too precise", in: "0.001", wantErr: true},
+		{name: "Exponent", in: "1e3", wantErr: true},
+		{name: "Huge exponent", in: "1e400", wantErr: true},
+		{name: "Hex float", in: "0x1p-2", wantErr: true},
+		{name: "NaN", in: "NaN", wantErr: true},
+		{name: "Inf", in: "Inf", wantErr: true},
+		{name: "Empty", in: "", wantErr: true},
+		{name: "Overflow", in: "92233720368547758.08", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			v, err := Parse(test.in)
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrInvalid)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.want, v)
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "500.50", Format(50050))
+	assert.Equal(t, "42.00", Format(4200))
+	assert.Equal(t, "-0.05", Format(-5))
+	assert.Equal(t, int64(1), FromFloat(0.005+0.004))
+	assert.InDelta(t, 751.25, ToFloat(75125), 1e-9)
+}
@@ -0,0 +1,183 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/ports"
+)
+
+type Managment interface {
+	Create(ctx context.Context, userID int64, name string, scopes []string) (*Key, string, error)
+	List(ctx context.Context, userID int64) ([]Key, error)
+	Revoke(ctx context.Context, userID, keyID int64) error
+	Authenticate(ctx context.Context, key string) (*Key, error)
+}
+
+const (
+	ScopeOrdersRead       = "orders:read"
+	ScopeOrdersWrite      = "orders:write"
+	ScopeBalanceRead      = "balance:read"
+	ScopeWithdrawalsRead  = "withdrawals:read"
+	ScopeWithdrawalsWrite = "withdrawals:write"
+	ScopeWebhooksRead     = "webhooks:read"
+	ScopeWebhooksWrite    = "webhooks:write"
+)
+
+var Scopes = []string{
+	ScopeOrdersRead,
+	ScopeOrdersWrite,
+	ScopeBalanceRead,
+	ScopeWithdrawalsRead,
+	ScopeWithdrawalsWrite,
+	ScopeWebhooksRead,
+	ScopeWebhooksWrite,
+}
+
+const (
+	keyPrefix = "gm_"
+	keyLength = 32
+)
+
+//nolint:govet //incorrectly detects alignment
+type Key struct {
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	Name       string
+	Scopes     []string
+	ID         int64
+	UserID     int64
+}
+
+func (k *Key) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+var (
+	ErrEmptyName    = errors.New("api key name is empty")
+	ErrEmptyScopes  = errors.New("api key scopes are empty")
+	ErrUnknownScope = errors.New("unknown api key scope")
+	ErrNotFound     = errors.New("api key not found")
+	ErrInvalidKey   = errors.New("invalid api key")
+)
+
+type apikey struct {
+	storage ports.APIKeyStorage
+}
+
+func New(storage ports.APIKeyStorage) Managment {
+	return &apikey{
+		storage: storage,
+	}
+}
+
+// Create создаёт ключ и возвращает его в открытом виде. В хранилище попадает только хеш ключа,
+// поэтому получить открытый ключ повторно невозможно.
+func (a *apikey) Create(ctx context.Context, userID int64, name string, scopes []string) (*Key, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", ErrEmptyName
+	}
+
+	if len(scopes) == 0 {
+		return nil, "", ErrEmptyScopes
+	}
+
+	for _, s := range scopes {
+		if !slices.Contains(Scopes, s) {
+			return nil, "", fmt.Errorf("%w:%s", ErrUnknownScope, s)
+		}
+	}
+
+	b := make([]byte, keyLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key:%w", err)
+	}
+	key := keyPrefix + hex.EncodeToString(b)
+
+	dbKey, err := a.storage.CreateAPIKey(ctx, userID, name, hash(key), scopes)
+	if err != nil {
+		return nil, "", fmt.Errorf("storage error of create api key:%w", err)
+	}
+
+	return toKey(dbKey), key, nil
+}
+
+func (a *apikey) List(ctx context.Context, userID int64) ([]Key, error) {
+	keys := []Key{}
+
+	dbKeys, err := a.storage.GetAPIKeys(ctx, userID)
+	if err != nil {
+		return keys, fmt.Errorf("storage error of get api keys:%w", err)
+	}
+
+	for i := range dbKeys {
+		keys = append(keys, *toKey(&dbKeys[i]))
+	}
+
+	return keys, nil
+}
+
+func (a *apikey) Revoke(ctx context.Context, userID, keyID int64) error {
+	err := a.storage.RevokeAPIKey(ctx, userID, keyID)
+	if err != nil {
+		if errors.Is(err, ports.ErrAPIKeyNotFound) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("storage error of revoke api key:%w", err)
+	}
+
+	return nil
+}
+
+func (a *apikey) Authenticate(ctx context.Context, key string) (*Key, error) {
+	if !strings.HasPrefix(key, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	dbKey, err := a.storage.UseAPIKey(ctx, hash(key))
+	if err != nil {
+		if errors.Is(err, ports.ErrAPIKeyNotFound) {
+			return nil, ErrInvalidKey
+		}
+
+		return nil, fmt.Errorf("storage error of use api key:%w", err)
+	}
+
+	return toKey(dbKey), nil
+}
+
+// hash возвращает sha256 от ключа. Ключи имеют высокую энтропию, поэтому медленный хеш (bcrypt) не нужен.
+func hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func toKey(k *ports.APIKey) *Key {
+	key := &Key{
+		ID:        k.ID,
+		UserID:    k.UserID,
+		Name:      k.Name,
+		Scopes:    k.Scopes,
+		CreatedAt: k.CreatedAt,
+	}
+
+	if k.LastUsedAt.Valid {
+		key.LastUsedAt = &k.LastUsedAt.Time
+	}
+
+	if k.RevokedAt.Valid {
+		key.RevokedAt = &k.RevokedAt.Time
+	}
+
+	return key
+}
@@ -0,0 +1,131 @@
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubStorage хранит ключи в памяти по хешу, отозванные ключи не аутентифицируются, как и в БД.
+type stubStorage struct {
+	keys    map[string]*ports.APIKey
+	revoked map[int64]bool
+}
+
+func newStubStorage() *stubStorage {
+	return &stubStorage{
+		keys:    make(map[string]*ports.APIKey),
+		revoked: make(map[int64]bool),
+	}
+}
+
+func (s *stubStorage) CreateAPIKey(_ context.Context, userID int64, name, hash string,
+	scopes []string) (*ports.APIKey, error) {
+	k := &ports.APIKey{ID: int64(len(s.keys) + 1), UserID: userID, Name: name, Scopes: scopes}
+	s.keys[hash] = k
+	return k, nil
+}
+
+func (s *stubStorage) UseAPIKey(_ context.Context, hash string) (*ports.APIKey, error) {
+	k, ok := s.keys[hash]
+	if !ok || s.revoked[k.ID] {
+		return nil, ports.ErrAPIKeyNotFound
+	}
+	return k, nil
+}
+
+func (s *stubStorage) GetAPIKeys(_ context.Context, _ int64) ([]ports.APIKey, error) {
+	return nil, nil
+}
+
+func (s *stubStorage) RevokeAPIKey(_ context.Context, userID, keyID int64) error {
+	for _, k := range s.keys {
+		if k.ID == keyID && k.UserID == userID && !s.revoked[keyID] {
+			s.revoked[keyID] = true
+			return nil
+		}
+	}
+	return ports.ErrAPIKeyNotFound
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyName string
+		scopes  []string
+		err     error
+	}{
+		{name: "Valid", keyName: "crm", scopes: []string{ScopeOrdersRead, ScopeBalanceRead}},
+		{name: "Empty name", keyName: " ", scopes: []string{ScopeOrdersRead}, err: ErrEmptyName},
+		{name: "Empty scopes", keyName: "crm", err: ErrEmptyScopes},
+		{name: "Unknown scope", keyName: "crm", scopes: []string{ScopeOrdersRead, "admin"}, err: ErrUnknownScope},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newStubStorage()
+			a := New(s)
+
+			k, key, err := a.Create(context.Background(), 1, test.keyName, test.scopes)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				assert.Empty(t, s.keys)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(key, keyPrefix))
+			assert.Len(t, key, len(keyPrefix)+2*keyLength)
+			assert.Equal(t, test.scopes, k.Scopes)
+
+			// В хранилище попадает только sha256 от ключа.
+			sum := sha256.Sum256([]byte(key))
+			require.Contains(t, s.keys, hex.EncodeToString(sum[:]))
+			assert.NotContains(t, s.keys, key)
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	a := New(newStubStorage())
+
+	k, key, err := a.Create(ctx, 1, "crm", []string{ScopeOrdersRead})
+	require.NoError(t, err)
+
+	got, err := a.Authenticate(ctx, key)
+	require.NoError(t, err)
+	assert.Equal(t, k.ID, got.ID)
+	assert.Equal(t, int64(1), got.UserID)
+
+	_, err = a.Authenticate(ctx, strings.TrimPrefix(key, keyPrefix))
+	assert.ErrorIs(t, err, ErrInvalidKey, "key without prefix")
+
+	_, err = a.Authenticate(ctx, key+"0")
+	assert.ErrorIs(t, err, ErrInvalidKey, "unknown key")
+
+	err = a.Revoke(ctx, 2, k.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "key of other user")
+
+	require.NoError(t, a.Revoke(ctx, 1, k.ID))
+	_, err = a.Authenticate(ctx, key)
+	assert.ErrorIs(t, err, ErrInvalidKey, "revoked key")
+
+	err = a.Revoke(ctx, 1, k.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "already revoked key")
+}
+
+func TestHasScope(t *testing.T) {
+	k := &Key{Scopes: []string{ScopeOrdersRead, ScopeWebhooksWrite}}
+
+	assert.True(t, k.HasScope(ScopeOrdersRead))
+	assert.True(t, k.HasScope(ScopeWebhooksWrite))
+	assert.False(t, k.HasScope(ScopeOrdersWrite))
+	assert.False(t, k.HasScope(ScopeWebhooksRead))
+}
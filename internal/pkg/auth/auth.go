@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -9,41 +11,53 @@ import (
 )
 
 type UserAuthentication interface {
-	GenerateToken(userID int64) (string, error)
-	GetUserID(token string) (int64, error)
+	GenerateToken(userID, sessionID int64, role string) (string, error)
+	GetClaims(token string) (*Claims, error)
 	GeneratePasswordHash(password string) (string, error)
 	CheckPasswordHash(password, hash string) error
 }
 
 const (
-	tokenTTL    = 24 * time.Hour
-	hashingCost = 10
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
 )
 
 type auth struct {
-	secretKey string
-	tokenTTL  time.Duration
+	// verificationKeys дополнительные ключи проверки подписи токенов, например прежние ключи подписи
+	// на время их замены. Меняются без перезапуска сервера через SetVerificationKeys.
+	verificationKeys atomic.Pointer[[]string]
+	secretKey        string
+	tokenTTL         time.Duration
+	hashingCost      int
 }
 
-func New(secretKey string) *auth {
+// New создаёт аутентификацию с ключом подписи токенов secretKey, временем жизни токенов tokenTTL
+// и стоимостью хеширования паролей bcrypt hashingCost.
+func New(secretKey string, tokenTTL time.Duration, hashingCost int) *auth {
 	return &auth{
-		secretKey: secretKey,
-		tokenTTL:  tokenTTL,
+		secretKey:   secretKey,
+		tokenTTL:    tokenTTL,
+		hashingCost: hashingCost,
 	}
 }
 
 type Claims struct {
 	jwt.StandardClaims
-	UserID int64 `json:"user_id"`
+	Role      string `json:"role"`
+	UserID    int64  `json:"user_id"`
+	SessionID int64  `json:"sid"`
 }
 
-func (a *auth) GenerateToken(userID int64) (string, error) {
+func (a *auth) GenerateToken(userID, sessionID int64, role string) (string, error) {
 	claims := Claims{
 		jwt.StandardClaims{
 			ExpiresAt: time.Now().Add(a.tokenTTL).Unix(),
 			IssuedAt:  time.Now().Unix(),
 		},
+		role,
 		userID,
+		sessionID,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -55,28 +69,60 @@ func (a *auth) GenerateToken(userID int64) (string, error) {
 	return signedToken, nil
 }
 
-func (a *auth) GetUserID(tokenString string) (int64, error) {
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims,
-		func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-			}
-			return []byte(a.secretKey), nil
-		})
+// SetVerificationKeys задаёт дополнительные ключи проверки подписи токенов. Токены по-прежнему
+// подписываются только ключом secretKey.
+func (a *auth) SetVerificationKeys(keys []string) {
+	keys = append([]string(nil), keys...)
+	a.verificationKeys.Store(&keys)
+}
+
+func (a *auth) GetClaims(tokenString string) (*Claims, error) {
+	keys := []string{a.secretKey}
+	if extra := a.verificationKeys.Load(); extra != nil {
+		keys = append(keys, *extra...)
+	}
+
+	var (
+		claims *Claims
+		token  *jwt.Token
+		err    error
+	)
+	for _, key := range keys {
+		claims = &Claims{}
+		token, err = jwt.ParseWithClaims(tokenString, claims,
+			func(t *jwt.Token) (interface{}, error) {
+				if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+					return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+				}
+				return []byte(key), nil
+			})
+		if !isSignatureInvalid(err) {
+			break
+		}
+	}
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse token with claims, %w", err)
+		return nil, fmt.Errorf("failed to parse token with claims, %w", err)
 	}
 
 	if !token.Valid {
-		return 0, fmt.Errorf("token not valid")
+		return nil, fmt.Errorf("token not valid")
 	}
 
-	return claims.UserID, nil
+	if claims.Role == "" {
+		claims.Role = RoleUser
+	}
+
+	return claims, nil
+}
+
+// isSignatureInvalid сообщает, что токен не прошёл проверку подписи и его стоит проверить следующим ключом.
+func isSignatureInvalid(err error) bool {
+	var ve *jwt.ValidationError
+	return errors.As(err, &ve) && ve.Errors&jwt.ValidationErrorSignatureInvalid != 0
 }
 
 func (a *auth) GeneratePasswordHash(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), hashingCost)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), a.hashingCost)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate from password, %w", err)
 	}
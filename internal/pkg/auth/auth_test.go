@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetClaimsVerificationKeys(t *testing.T) {
+	old := New("old-secret", time.Hour, 4)
+	token, err := old.GenerateToken(1, 2, RoleAdmin)
+	require.NoError(t, err)
+
+	a := New("new-secret", time.Hour, 4)
+
+	_, err = a.GetClaims(token)
+	assert.Error(t, err, "token signed with unknown key")
+
+	a.SetVerificationKeys([]string{"other-secret", "old-secret"})
+	claims, err := a.GetClaims(token)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), claims.UserID)
+	assert.Equal(t, int64(2), claims.SessionID)
+	assert.Equal(t, RoleAdmin, claims.Role)
+
+	a.SetVerificationKeys([]string{"other-secret"})
+	_, err = a.GetClaims(token)
+	assert.Error(t, err, "token signed with removed key")
+}
+
+func TestGetClaimsSecretKey(t *testing.T) {
+	a := New("secret", time.Hour, 4)
+	a.SetVerificationKeys([]string{"old-secret"})
+
+	token, err := a.GenerateToken(1, 2, "")
+	require.NoError(t, err)
+
+	claims, err := a.GetClaims(token)
+	require.NoError(t, err)
+	assert.Equal(t, RoleUser, claims.Role)
+
+	expired := New("secret", -time.Hour, 4)
+	token, err = expired.GenerateToken(1, 2, RoleUser)
+	require.NoError(t, err)
+
+	_, err = a.GetClaims(token)
+	assert.Error(t, err, "expired token")
+}
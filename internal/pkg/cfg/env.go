@@ -0,0 +1,136 @@
+package cfg
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// loadEnv перекрывает значения cfg заданными переменными окружения и возвращает ошибки разбора
+// всех неверных значений.
+func loadEnv(cfg *Config) []error {
+	var e envLoader
+
+	e.str("RUN_ADDRESS", &cfg.RunAddress)
+	e.str("GRPC_ADDRESS", &cfg.GRPCAddress)
+	e.str("METRICS_ADDRESS", &cfg.MetricsAddress)
+	e.str("DATABASE_URI", &cfg.DatabaseURI)
+	e.str("MIGRATION_DATABASE_URI", &cfg.MigrationDatabaseURI)
+	e.bool("AUTO_MIGRATE", &cfg.AutoMigrate)
+	e.str("ACCRUAL_SYSTEM_ADDRESS", &cfg.AccrualSystemAddress)
+	e.str("SECRET_KEY", &cfg.SecretKey)
+	e.list("JWT_VERIFICATION_KEYS", &cfg.JWTVerificationKeys)
+	e.duration("TOKEN_TTL", &cfg.TokenTTL)
+	e.int("BCRYPT_COST", &cfg.BcryptCost)
+	e.duration("POLL_INTERVAL", &cfg.PollInterval)
+	e.int("POLL_CONCURRENCY", &cfg.PollConcurrency)
+	e.duration("ACCRUAL_TIMEOUT", &cfg.AccrualTimeout)
+	e.int32("DB_MAX_CONNS", &cfg.DBMaxConns)
+	e.int32("DB_MIN_CONNS", &cfg.DBMinConns)
+	e.duration("HTTP_READ_HEADER_TIMEOUT", &cfg.HTTPReadHeaderTimeout)
+	e.duration("HTTP_IDLE_TIMEOUT", &cfg.HTTPIdleTimeout)
+	e.int64("MAX_BODY_SIZE", &cfg.MaxBodySize)
+	e.duration("SHUTDOWN_TIMEOUT", &cfg.ShutdownTimeout)
+	e.str("OIDC_ISSUER", &cfg.OIDCIssuer)
+	e.str("OIDC_CLIENT_ID", &cfg.OIDCClientID)
+	e.str("OIDC_CLIENT_SECRET", &cfg.OIDCClientSecret)
+	e.str("OIDC_REDIRECT_URL", &cfg.OIDCRedirectURL)
+	e.str("OTEL_TRACES_EXPORTER", &cfg.TracesExporter)
+	e.str("LOG_LEVEL", &cfg.LogLevel)
+	e.str("LOG_FORMAT", &cfg.LogFormat)
+	e.bool("DEV_MODE", &cfg.DevMode)
+
+	return e.errs
+}
+
+// envLoader читает переменные окружения, накапливая ошибки разбора.
+type envLoader struct {
+	errs []error
+}
+
+func (e *envLoader) str(name string, dst *string) {
+	v, ok := os.LookupEnv(name)
+	if ok {
+		*dst = v
+	}
+}
+
+// list читает список значений, разделённых запятыми. Пустые значения пропускаются.
+func (e *envLoader) list(name string, dst *[]string) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return
+	}
+
+	var values []string
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			values = append(values, item)
+		}
+	}
+	*dst = values
+}
+
+func (e *envLoader) duration(name string, dst *time.Duration) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("invalid %s:%q, expected duration such as 10s", name, v))
+		return
+	}
+	*dst = d
+}
+
+func (e *envLoader) int(name string, dst *int) {
+	var n int64
+	if e.parseInt(name, 0, &n) {
+		*dst = int(n)
+	}
+}
+
+func (e *envLoader) int32(name string, dst *int32) {
+	var n int64
+	if e.parseInt(name, 32, &n) {
+		*dst = int32(n)
+	}
+}
+
+func (e *envLoader) int64(name string, dst *int64) {
+	e.parseInt(name, 64, dst)
+}
+
+func (e *envLoader) parseInt(name string, bitSize int, dst *int64) bool {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return false
+	}
+
+	n, err := strconv.ParseInt(v, 10, bitSize)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("invalid %s:%q, expected integer", name, v))
+		return false
+	}
+	*dst = n
+	return true
+}
+
+func (e *envLoader) bool(name string, dst *bool) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return
+	}
+
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("invalid %s:%q, expected boolean", name, v))
+		return
+	}
+	*dst = b
+}
@@ -0,0 +1,53 @@
+package cfg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile перекрывает значения cfg заданными в файле конфигурации path. Формат определяется
+// по расширению: .yaml, .yml или .toml. Неизвестные ключи считаются ошибкой.
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file:%w", err)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		d := yaml.NewDecoder(bytes.NewReader(data))
+		d.KnownFields(true)
+		err = d.Decode(cfg)
+		if errors.Is(err, io.EOF) {
+			// Пустой файл.
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse config file %s:%w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("failed to parse config file %s:%w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) != 0 {
+			keys := make([]string, 0, len(undecoded))
+			for _, k := range undecoded {
+				keys = append(keys, k.String())
+			}
+			return fmt.Errorf("unknown keys in config file %s:%s", path, strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("unsupported config file format:%q, expected .yaml, .yml or .toml", ext)
+	}
+
+	return nil
+}
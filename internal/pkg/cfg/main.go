@@ -1,60 +1,255 @@
 package cfg
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"net/url"
 	"os"
+	"regexp"
+	"time"
 
 	"github.com/rs/zerolog/log"
 )
 
+// Значения настроек по умолчанию.
+const (
+	DefaultRunAddress            = "localhost:8080"
+	DefaultSecretKey             = "defaultSecretKey"
+	DefaultTokenTTL              = 24 * time.Hour
+	DefaultBcryptCost            = 10
+	DefaultPollInterval          = time.Second
+	DefaultPollConcurrency       = 1
+	DefaultAccrualTimeout        = 10 * time.Second
+	DefaultDBMaxConns            = 10
+	DefaultHTTPReadHeaderTimeout = 10 * time.Second
+	DefaultHTTPIdleTimeout       = 2 * time.Minute
+	DefaultMaxBodySize           = 1 << 20
+	DefaultLogLevel              = "info"
+	DefaultLogFormat             = "json"
+	// DefaultShutdownTimeout время на остановку сервера по умолчанию: завершение обрабатываемых запросов
+	// и заданий опроса системы расчёта начислений.
+	DefaultShutdownTimeout = 20 * time.Second
+)
+
+// Config настройки сервера. Источники в порядке убывания приоритета: флаги командной строки, переменные
+// окружения, файл конфигурации (флаг -c или переменная окружения CONFIG), значения по умолчанию.
+// Имена ключей файла конфигурации указаны в тегах yaml и toml.
+//
+//nolint:govet //fields are grouped by meaning
 type Config struct {
-	RunAddress           string
-	DatabaseURI          string
-	AccrualSystemAddress string
-	SecretKey            string
+	RunAddress            string        `yaml:"run_address" toml:"run_address"`
+	GRPCAddress           string        `yaml:"grpc_address" toml:"grpc_address"`
+	MetricsAddress        string        `yaml:"metrics_address" toml:"metrics_address"`
+	DatabaseURI           string        `yaml:"database_uri" toml:"database_uri"`
+	MigrationDatabaseURI  string        `yaml:"migration_database_uri" toml:"migration_database_uri"`
+	AutoMigrate           bool          `yaml:"auto_migrate" toml:"auto_migrate"`
+	AccrualSystemAddress  string        `yaml:"accrual_system_address" toml:"accrual_system_address"`
+	SecretKey             string        `yaml:"secret_key" toml:"secret_key"`
+	JWTVerificationKeys   []string      `yaml:"jwt_verification_keys" toml:"jwt_verification_keys"`
+	TokenTTL              time.Duration `yaml:"token_ttl" toml:"token_ttl"`
+	BcryptCost            int           `yaml:"bcrypt_cost" toml:"bcrypt_cost"`
+	PollInterval          time.Duration `yaml:"poll_interval" toml:"poll_interval"`
+	PollConcurrency       int           `yaml:"poll_concurrency" toml:"poll_concurrency"`
+	AccrualTimeout        time.Duration `yaml:"accrual_timeout" toml:"accrual_timeout"`
+	DBMaxConns            int32         `yaml:"db_max_conns" toml:"db_max_conns"`
+	DBMinConns            int32         `yaml:"db_min_conns" toml:"db_min_conns"`
+	HTTPReadHeaderTimeout time.Duration `yaml:"http_read_header_timeout" toml:"http_read_header_timeout"`
+	HTTPIdleTimeout       time.Duration `yaml:"http_idle_timeout" toml:"http_idle_timeout"`
+	MaxBodySize           int64         `yaml:"max_body_size" toml:"max_body_size"`
+	ShutdownTimeout       time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	OIDCIssuer            string        `yaml:"oidc_issuer" toml:"oidc_issuer"`
+	OIDCClientID          string        `yaml:"oidc_client_id" toml:"oidc_client_id"`
+	OIDCClientSecret      string        `yaml:"oidc_client_secret" toml:"oidc_client_secret"`
+	OIDCRedirectURL       string        `yaml:"oidc_redirect_url" toml:"oidc_redirect_url"`
+	TracesExporter        string        `yaml:"traces_exporter" toml:"traces_exporter"`
+	LogLevel              string        `yaml:"log_level" toml:"log_level"`
+	LogFormat             string        `yaml:"log_format" toml:"log_format"`
+	DevMode               bool          `yaml:"dev_mode" toml:"dev_mode"`
 }
 
-func New() (*Config, error) {
-	cfg := Config{
-		SecretKey: "defaultSecretKey",
+// Default возвращает конфигурацию со значениями по умолчанию.
+func Default() Config {
+	return Config{
+		RunAddress:            DefaultRunAddress,
+		AutoMigrate:           true,
+		SecretKey:             DefaultSecretKey,
+		TokenTTL:              DefaultTokenTTL,
+		BcryptCost:            DefaultBcryptCost,
+		PollInterval:          DefaultPollInterval,
+		PollConcurrency:       DefaultPollConcurrency,
+		AccrualTimeout:        DefaultAccrualTimeout,
+		DBMaxConns:            DefaultDBMaxConns,
+		HTTPReadHeaderTimeout: DefaultHTTPReadHeaderTimeout,
+		HTTPIdleTimeout:       DefaultHTTPIdleTimeout,
+		MaxBodySize:           DefaultMaxBodySize,
+		ShutdownTimeout:       DefaultShutdownTimeout,
+		LogLevel:              DefaultLogLevel,
+		LogFormat:             DefaultLogFormat,
 	}
+}
 
-	ra, ok := os.LookupEnv("RUN_ADDRESS")
-	if ok {
-		cfg.RunAddress = ra
+// commandLine флаги командной строки, явно заданные при вызове New, по именам. Используются повторно в Reload.
+var commandLine map[string]string
+
+// flagUsages описания флагов командной строки по именам.
+var flagUsages = map[string]string{
+	"c": "путь к файлу конфигурации YAML (.yaml, .yml) или TOML (.toml): переменная окружения ОС CONFIG или флаг -c",
+	"a": "адрес и порт запуска сервиса: переменная окружения ОС RUN_ADDRESS или флаг -a",
+	"g": "адрес и порт запуска gRPC API, если не задан - gRPC API отключён: переменная окружения ОС GRPC_ADDRESS " +
+		"или флаг -g",
+	"d": "адрес подключения к базе данных: переменная окружения ОС DATABASE_URI или флаг -d",
+	"r": "адрес системы расчёта начислений: переменная окружения ОС ACCRUAL_SYSTEM_ADDRESS или флаг -r",
+	"l": "уровень логирования (trace, debug, info, warn, error): переменная окружения ОС LOG_LEVEL или флаг -l",
+}
+
+// New собирает конфигурацию из флагов командной строки args, переменных окружения и файла конфигурации
+// и проверяет её. Ошибки всех источников и всех неверных настроек возвращаются вместе.
+func New(args []string) (*Config, error) {
+	flags, rest, err := parseFlags(args, "c", "a", "g", "d", "r", "l")
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("unknown flags")
 	}
 
-	duri, ok := os.LookupEnv("DATABASE_URI")
-	if ok {
-		cfg.DatabaseURI = duri
+	commandLine = flags
+	return load(commandLine, (*Config).Validate)
+}
+
+// NewTool собирает конфигурацию служебных команд (gophermart migrate, gophermartctl) из флагов командной
+// строки args (-c, -d и -l), переменных окружения и файла конфигурации. Проверяются только настройки БД,
+// хеширования паролей и логирования. Возвращает также аргументы, следующие за флагами.
+func NewTool(args []string) (*Config, []string, error) {
+	flags, rest, err := parseFlags(args, "c", "d", "l")
+	if err != nil {
+		return nil, nil, err
 	}
 
-	asa, ok := os.LookupEnv("ACCRUAL_SYSTEM_ADDRESS")
-	if ok {
-		cfg.AccrualSystemAddress = asa
+	cfg, err := load(flags, (*Config).validateTool)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	flag.StringVar(&cfg.RunAddress, "a", cfg.RunAddress,
-		"адрес и порт запуска сервиса: переменная окружения ОС RUN_ADDRESS или флаг -a")
-	flag.StringVar(&cfg.DatabaseURI, "d", cfg.DatabaseURI,
-		"адрес подключения к базе данных: переменная окружения ОС DATABASE_URI или флаг -d")
-	flag.StringVar(&cfg.AccrualSystemAddress, "r", cfg.AccrualSystemAddress,
-		"адрес системы расчёта начислений: переменная окружения ОС ACCRUAL_SYSTEM_ADDRESS или флаг -r")
+	return cfg, rest, nil
+}
 
-	flag.Parse()
+// Reload заново читает файл конфигурации и переменные окружения и применяет флаги командной строки,
+// заданные при вызове New. Должен вызываться после New.
+func Reload() (*Config, error) {
+	return load(commandLine, (*Config).Validate)
+}
 
-	if len(flag.Args()) != 0 {
-		return nil, fmt.Errorf("unknown flags")
+// parseFlags разбирает флаги names из args. Возвращает явно заданные флаги по именам и аргументы после флагов.
+func parseFlags(args []string, names ...string) (map[string]string, []string, error) {
+	defaults := map[string]string{
+		"a": DefaultRunAddress,
+		"l": DefaultLogLevel,
+	}
+	for _, name := range names {
+		flag.String(name, defaults[name], flagUsages[name])
+	}
+
+	err := flag.CommandLine.Parse(args)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse flags:%w", err)
+	}
+
+	// Значения по умолчанию флагов не должны перекрывать файл и переменные окружения, поэтому сохраняются
+	// только явно заданные флаги.
+	flags := make(map[string]string)
+	flag.Visit(func(f *flag.Flag) {
+		flags[f.Name] = f.Value.String()
+	})
+
+	return flags, flag.Args(), nil
+}
+
+func load(flags map[string]string, validate func(c *Config) []error) (*Config, error) {
+	cfg := Default()
+
+	var errs []error
+	configPath := os.Getenv("CONFIG")
+	if v, ok := flags["c"]; ok {
+		configPath = v
+	}
+	if configPath != "" {
+		err := loadFile(configPath, &cfg)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	errs = append(errs, loadEnv(&cfg)...)
+
+	targets := map[string]*string{
+		"a": &cfg.RunAddress,
+		"g": &cfg.GRPCAddress,
+		"d": &cfg.DatabaseURI,
+		"r": &cfg.AccrualSystemAddress,
+		"l": &cfg.LogLevel,
+	}
+	for name, v := range flags {
+		if dst, ok := targets[name]; ok {
+			*dst = v
+		}
+	}
+
+	errs = append(errs, validate(&cfg)...)
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
 	}
 
 	return &cfg, nil
 }
 
+// Print пишет в лог конфигурацию без секретов: ключ подписи токенов и секрет клиента OIDC не выводятся,
+// пароль в адресе подключения к базе данных маскируется.
 func (c *Config) Print() {
-	log.Debug().
+	log.Info().
 		Str("cfg.RunAddress", c.RunAddress).
-		Str("cfg.DatabaseURI", c.DatabaseURI).
+		Str("cfg.GRPCAddress", c.GRPCAddress).
+		Str("cfg.MetricsAddress", c.MetricsAddress).
+		Str("cfg.DatabaseURI", redactDSN(c.DatabaseURI)).
+		Str("cfg.MigrationDatabaseURI", redactDSN(c.MigrationDatabaseURI)).
+		Bool("cfg.AutoMigrate", c.AutoMigrate).
 		Str("cfg.AccrualSystemAddress", c.AccrualSystemAddress).
+		Dur("cfg.TokenTTL", c.TokenTTL).
+		Int("cfg.BcryptCost", c.BcryptCost).
+		Int("cfg.JWTVerificationKeys", len(c.JWTVerificationKeys)).
+		Dur("cfg.PollInterval", c.PollInterval).
+		Int("cfg.PollConcurrency", c.PollConcurrency).
+		Dur("cfg.AccrualTimeout", c.AccrualTimeout).
+		Int32("cfg.DBMaxConns", c.DBMaxConns).
+		Int32("cfg.DBMinConns", c.DBMinConns).
+		Dur("cfg.HTTPReadHeaderTimeout", c.HTTPReadHeaderTimeout).
+		Dur("cfg.HTTPIdleTimeout", c.HTTPIdleTimeout).
+		Str("cfg.OIDCIssuer", c.OIDCIssuer).
+		Str("cfg.OIDCClientID", c.OIDCClientID).
+		Str("cfg.OIDCRedirectURL", c.OIDCRedirectURL).
+		Str("cfg.TracesExporter", c.TracesExporter).
+		Str("cfg.LogLevel", c.LogLevel).
+		Str("cfg.LogFormat", c.LogFormat).
+		Int64("cfg.MaxBodySize", c.MaxBodySize).
+		Dur("cfg.ShutdownTimeout", c.ShutdownTimeout).
+		Bool("cfg.DevMode", c.DevMode).
 		Msg("printConfig")
 }
+
+// dsnPassword пароль в DSN формата ключ=значение, например "host=db user=u password=secret".
+var dsnPassword = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// redactDSN маскирует пароль в адресе подключения к базе данных формата URL или ключ=значение.
+func redactDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err == nil && u.Scheme != "" {
+		if q := u.Query(); q.Has("password") {
+			q.Set("password", "xxxxx")
+			u.RawQuery = q.Encode()
+		}
+		return u.Redacted()
+	}
+
+	return dsnPassword.ReplaceAllString(dsn, "${1}xxxxx")
+}
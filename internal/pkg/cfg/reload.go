@@ -0,0 +1,94 @@
+package cfg
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// reloadable ключи настроек, изменения которых применяются по SIGHUP без перезапуска сервиса.
+var reloadable = map[string]bool{
+	"log_level":              true,
+	"poll_interval":          true,
+	"poll_concurrency":       true,
+	"accrual_system_address": true,
+	"accrual_timeout":        true,
+	"jwt_verification_keys":  true,
+}
+
+// secrets ключи настроек, значения которых не пишутся в лог.
+var secrets = map[string]bool{
+	"secret_key":            true,
+	"jwt_verification_keys": true,
+	"oidc_client_secret":    true,
+}
+
+// Change изменение одной настройки. Key - ключ файла конфигурации, Old и New - значения для лога:
+// секреты скрыты, пароль в адресе БД заменён.
+type Change struct {
+	Key        string
+	Old        string
+	New        string
+	Reloadable bool
+}
+
+// Changes возвращает изменённые в next настройки относительно c в порядке полей Config.
+func (c *Config) Changes(next *Config) []Change {
+	var changes []Change
+
+	cur, nxt := reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < cur.NumField(); i++ {
+		if reflect.DeepEqual(cur.Field(i).Interface(), nxt.Field(i).Interface()) {
+			continue
+		}
+
+		key := fieldKey(cur.Type().Field(i))
+		changes = append(changes, Change{
+			Key:        key,
+			Old:        formatValue(key, cur.Field(i).Interface()),
+			New:        formatValue(key, nxt.Field(i).Interface()),
+			Reloadable: reloadable[key],
+		})
+	}
+
+	return changes
+}
+
+// Merge возвращает копию c, в которой перезагружаемые настройки взяты из next. Остальные изменения
+// вступят в силу только после перезапуска. Объединённая конфигурация проверяется целиком: next проверен
+// с новыми значениями неперезагружаемых настроек, например poll_concurrency с новым db_max_conns, а работать
+// сервис будет со старыми.
+func (c *Config) Merge(next *Config) (*Config, error) {
+	merged := *c
+
+	dst, src := reflect.ValueOf(&merged).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		if reloadable[fieldKey(dst.Type().Field(i))] {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+
+	if errs := merged.Validate(); len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return &merged, nil
+}
+
+// fieldKey возвращает ключ файла конфигурации поля f.
+func fieldKey(f reflect.StructField) string {
+	key, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	return key
+}
+
+func formatValue(key string, v any) string {
+	switch {
+	case secrets[key]:
+		return "xxxxx"
+	case key == "database_uri" || key == "migration_database_uri":
+		return redactDSN(v.(string))
+	default:
+		return fmt.Sprint(v)
+	}
+}
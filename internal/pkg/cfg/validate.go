@@ -0,0 +1,128 @@
+package cfg
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/pkg/logging"
+	"github.com/k0st1a/gophermart/internal/pkg/tracing"
+	"github.com/rs/zerolog"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Validate проверяет все настройки и возвращает ошибки по каждой неверной.
+func (c *Config) Validate() []error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(c.RunAddress); err != nil {
+		add("run_address:%q is not host:port", c.RunAddress)
+	}
+	if c.GRPCAddress != "" {
+		if _, _, err := net.SplitHostPort(c.GRPCAddress); err != nil {
+			add("grpc_address:%q is not host:port", c.GRPCAddress)
+		}
+	}
+	if c.MetricsAddress != "" {
+		if _, _, err := net.SplitHostPort(c.MetricsAddress); err != nil {
+			add("metrics_address:%q is not host:port", c.MetricsAddress)
+		}
+	}
+	if !isHTTPURL(c.AccrualSystemAddress) {
+		add("accrual_system_address:%q is not http(s) URL", c.AccrualSystemAddress)
+	}
+	if c.SecretKey == "" {
+		add("secret_key is required")
+	}
+	if c.PollInterval < time.Millisecond {
+		add("poll_interval:%s must be at least 1ms", c.PollInterval)
+	}
+	if c.PollConcurrency < 1 {
+		add("poll_concurrency:%d must be positive", c.PollConcurrency)
+	}
+	// Каждое задание опроса держит соединение пула на всё время запроса к системе расчёта начислений,
+	// хотя бы одно соединение должно оставаться обработчикам API.
+	if c.PollConcurrency >= 1 && c.DBMaxConns >= 1 && c.PollConcurrency >= int(c.DBMaxConns) {
+		add("poll_concurrency:%d must be less than db_max_conns:%d", c.PollConcurrency, c.DBMaxConns)
+	}
+	if slices.Contains(c.JWTVerificationKeys, "") {
+		add("jwt_verification_keys must not contain empty keys")
+	}
+	if c.MaxBodySize <= 0 {
+		add("max_body_size:%d must be positive", c.MaxBodySize)
+	}
+
+	positive := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"token_ttl", c.TokenTTL},
+		{"accrual_timeout", c.AccrualTimeout},
+		{"http_read_header_timeout", c.HTTPReadHeaderTimeout},
+		{"http_idle_timeout", c.HTTPIdleTimeout},
+		{"shutdown_timeout", c.ShutdownTimeout},
+	}
+	for _, p := range positive {
+		if p.value <= 0 {
+			add("%s:%s must be positive", p.name, p.value)
+		}
+	}
+
+	if c.OIDCIssuer != "" {
+		if !isHTTPURL(c.OIDCIssuer) {
+			add("oidc_issuer:%q is not http(s) URL", c.OIDCIssuer)
+		}
+		if c.OIDCClientID == "" {
+			add("oidc_client_id is required with oidc_issuer")
+		}
+		if !isHTTPURL(c.OIDCRedirectURL) {
+			add("oidc_redirect_url:%q is not http(s) URL", c.OIDCRedirectURL)
+		}
+	}
+
+	exporters := []string{"", tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterConsole}
+	if !slices.Contains(exporters, c.TracesExporter) {
+		add("traces_exporter:%q must be one of none, otlp, console", c.TracesExporter)
+	}
+
+	return append(errs, c.validateTool()...)
+}
+
+// validateTool проверяет настройки, нужные служебным командам: БД, хеширование паролей и логирование.
+func (c *Config) validateTool() []error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.DatabaseURI == "" {
+		add("database_uri is required")
+	}
+	if c.DBMaxConns < 1 {
+		add("db_max_conns:%d must be positive", c.DBMaxConns)
+	}
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		add("bcrypt_cost:%d is out of range [%d, %d]", c.BcryptCost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if c.DBMinConns < 0 || c.DBMinConns > c.DBMaxConns {
+		add("db_min_conns:%d must be in range [0, db_max_conns]", c.DBMinConns)
+	}
+	if _, err := zerolog.ParseLevel(c.LogLevel); err != nil || c.LogLevel == "" {
+		add("log_level:%q must be one of trace, debug, info, warn, error", c.LogLevel)
+	}
+	if c.LogFormat != logging.FormatJSON && c.LogFormat != logging.FormatConsole {
+		add("log_format:%q must be one of json, console", c.LogFormat)
+	}
+
+	return errs
+}
+
+func isHTTPURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
@@ -4,9 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/k0st1a/gophermart/internal/pkg/events"
+	"github.com/k0st1a/gophermart/internal/pkg/logging"
+	"github.com/k0st1a/gophermart/internal/pkg/metrics"
+	"github.com/k0st1a/gophermart/internal/pkg/order"
 	"github.com/k0st1a/gophermart/internal/ports"
 	"github.com/rs/zerolog/log"
 )
@@ -14,39 +19,54 @@ import (
 type job struct {
 	storage ports.UpdateOrderStorage
 	client  ports.AccrualGetter
-	number  int
+	// locked получает результат блокировки заказа для опроса: nil, ports.ErrNoOrderToPoll или ошибку БД.
+	locked chan<- error
+	number int
 }
 
-func NewJob(number int, storage ports.UpdateOrderStorage, accrual ports.AccrualGetter) *job {
+// NewJob создаёт задание опроса одного заказа. Результат блокировки заказа отправляется в locked до запроса
+// к системе расчёта начислений, locked должен быть буферизованным.
+func NewJob(number int, storage ports.UpdateOrderStorage, accrual ports.AccrualGetter, locked chan<- error) *job {
 	return &job{
 		number:  number,
 		storage: storage,
 		client:  accrual,
+		locked:  locked,
 	}
 }
 
 func (j *job) Run(ctx context.Context) error {
-	log.Printf("Run job #%v", j.number)
+	l := log.Ctx(ctx).With().Int("job", j.number).Logger()
+	ctx = l.WithContext(ctx)
+	log.Ctx(ctx).Debug().Msg("run job")
 
 	tx, err := j.storage.BeginTx(ctx)
 	if err != nil {
-		return fmt.Errorf("storage error of begin transaction, error:%w", err)
+		err = fmt.Errorf("storage error of begin transaction, error:%w", err)
+		j.locked <- err
+		return err
 	}
 	defer func() {
 		_ = tx.Rollback(ctx)
 	}()
 
-	orderID, err := j.storage.GetNotProcessedOrderWithBlock(ctx, tx)
+	orderID, status, err := j.storage.GetNotProcessedOrderWithBlock(ctx, tx)
 	if err != nil {
-		return fmt.Errorf("storage error of get not processed order, error:%w", err)
+		err = fmt.Errorf("storage error of get not processed order, error:%w", err)
 	}
+	j.locked <- err
+	if err != nil {
+		return err
+	}
+	l = log.Ctx(ctx).With().Int64("order_id", orderID).Logger()
+	ctx = l.WithContext(ctx)
 
 	accrual, err := j.getAccrual(ctx, tx, orderID)
 	if err != nil {
 		return err
 	}
 
-	err = j.updateBalance(ctx, tx, orderID, accrual)
+	err = j.updateBalance(ctx, tx, orderID, status, accrual)
 	if err != nil {
 		return err
 	}
@@ -55,21 +75,14 @@ func (j *job) Run(ctx context.Context) error {
 }
 
 func (j *job) getAccrual(ctx context.Context, tx pgx.Tx, orderID int64) (*ports.Accrual, error) {
-	log.Printf("Job #%v, get accrual for orderID:%v", j.number, orderID)
-
 	orderString := strconv.FormatInt(orderID, 10)
 	accrual, err := j.client.Get(ctx, orderString)
 	if errors.Is(err, ports.ErrOrderNotRegistered) {
-		log.Printf("Job #%v, orderID:%v not registered in accrual", j.number, orderID)
+		log.Ctx(ctx).Info().Msg("order not registered in accrual")
 
-		err = j.storage.UpdateOrder(ctx, tx, orderID, "INVALID", 0)
+		err = j.invalidateOrder(ctx, tx, orderID)
 		if err != nil {
-			return nil, fmt.Errorf("storage error of update INVALID order, error:%w", err)
-		}
-
-		err = tx.Commit(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("storage error of commit transaction, error:%w", err)
+			return nil, err
 		}
 
 		return nil, fmt.Errorf("orderID:%v not registered in accrual", orderID)
@@ -78,56 +91,71 @@ func (j *job) getAccrual(ctx context.Context, tx pgx.Tx, orderID int64) (*ports.
 		return nil, fmt.Errorf("client error of get accrual for order, error:%w", err)
 	}
 
-	log.Printf("Job #%v, for orderID:%v, accrual:%+v", j.number, orderID, accrual)
-
 	if accrual.Order != orderString {
-		log.Printf("Job #%v, other accrual order from response, order from request:%v"+
-			", order from response:%v", j.number, orderString, accrual.Order)
+		log.Ctx(ctx).Warn().Str("response_order", accrual.Order).Msg("other accrual order from response")
 
-		err = j.storage.UpdateOrder(ctx, tx, orderID, "INVALID", 0)
+		err = j.invalidateOrder(ctx, tx, orderID)
 		if err != nil {
-			return nil, fmt.Errorf("storage error of update INVALID order, error:%w", err)
-		}
-
-		err = tx.Commit(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("storage error of commit transaction, error:%w", err)
+			return nil, err
 		}
 
 		return nil, fmt.Errorf("other accrual order from response, order from request:%v"+
 			", order from response:%v", orderString, accrual.Order)
 	}
 
+	// Отрицательное начисление списало бы баллы, заказ остаётся в очереди до корректного ответа.
+	if accrual.Accrual < 0 || math.IsNaN(accrual.Accrual) || math.IsInf(accrual.Accrual, 0) {
+		return nil, fmt.Errorf("invalid accrual:%v from response", accrual.Accrual)
+	}
+
 	return accrual, nil
 }
 
-func (j *job) updateBalance(ctx context.Context, tx pgx.Tx, orderID int64, ar *ports.Accrual) error {
-	log.Printf("Job #%v, update balance for orderID:%v", j.number, orderID)
-
+func (j *job) updateBalance(ctx context.Context, tx pgx.Tx, orderID int64, status string, ar *ports.Accrual) error {
 	userID, err := j.storage.GetUserIDByOrderWithBlock(ctx, tx, orderID)
 	if err != nil {
 		return fmt.Errorf("storage error of get user id by order, error:%w", err)
 	}
-	log.Printf("Job #%v, for orderID:%v, userID:%v", j.number, orderID, userID)
+	ctx = logging.SetUserID(ctx, userID)
 
 	balance, err := j.storage.GetBalanceWithBlock(ctx, tx, userID)
 	if err != nil {
 		return fmt.Errorf("storage error of get balance with block, error:%w", err)
 	}
-	log.Printf("Job #%v, for userID:%v, balance:%v", j.number, orderID, balance)
 
-	err = j.storage.UpdateOrder(ctx, tx, orderID, ar.Status, ar.Accrual)
+	newStatus := orderStatus(ar.Status)
+	err = j.storage.UpdateOrder(ctx, tx, orderID, newStatus, ar.Accrual)
 	if err != nil {
 		return fmt.Errorf("storage error of update order, error:%w", err)
 	}
 
+	err = j.storage.CreateOrderPoll(ctx, tx, orderID, ar.Status, ar.Accrual)
+	if err != nil {
+		return fmt.Errorf("storage error of create order poll, error:%w", err)
+	}
+
+	if newStatus != status {
+		err = j.createOrderEvent(ctx, tx, userID, orderID, newStatus, ar.Accrual)
+		if err != nil {
+			return err
+		}
+	}
+
 	if ar.Accrual != 0 {
-		log.Printf("Job #%v, accrual not 0 => update balance, userID:%v, new balance:%v",
-			j.number, userID, balance+ar.Accrual)
+		log.Ctx(ctx).Info().
+			Float64("accrual", ar.Accrual).
+			Float64("balance", balance+ar.Accrual).
+			Msg("update balance")
 		err = j.storage.UpdateBalance(ctx, tx, userID, balance+ar.Accrual)
 		if err != nil {
 			return fmt.Errorf("storage error of update balance, error:%w", err)
 		}
+
+		err = j.storage.CreateUserEvent(ctx, tx, userID, events.TypeBalanceUpdated,
+			events.Balance{Current: balance + ar.Accrual})
+		if err != nil {
+			return fmt.Errorf("storage error of create balance event, error:%w", err)
+		}
 	}
 
 	err = tx.Commit(ctx)
@@ -135,5 +163,73 @@ func (j *job) updateBalance(ctx context.Context, tx pgx.Tx, orderID int64, ar *p
 		return fmt.Errorf("storage error of commit transaction, error:%w", err)
 	}
 
+	if ar.Accrual > 0 {
+		metrics.PointsAccrued.Add(ar.Accrual)
+	}
+
+	return nil
+}
+
+// orderStatus возвращает статус заказа гофермарта для статуса системы расчёта начислений. REGISTERED (заказ
+// зарегистрирован, но расчёт не начат) в гофермарте соответствует PROCESSING, остальные статусы совпадают.
+// В истории опросов сохраняется исходный статус ответа.
+func orderStatus(accrualStatus string) string {
+	if accrualStatus == "REGISTERED" {
+		return order.StatusProcessing
+	}
+
+	return accrualStatus
+}
+
+// invalidateOrder помечает заказ как INVALID и фиксирует транзакцию.
+func (j *job) invalidateOrder(ctx context.Context, tx pgx.Tx, orderID int64) error {
+	err := j.storage.UpdateOrder(ctx, tx, orderID, order.StatusInvalid, 0)
+	if err != nil {
+		return fmt.Errorf("storage error of update INVALID order, error:%w", err)
+	}
+
+	err = j.storage.CreateOrderPoll(ctx, tx, orderID, order.StatusInvalid, 0)
+	if err != nil {
+		return fmt.Errorf("storage error of create order poll, error:%w", err)
+	}
+
+	userID, err := j.storage.GetUserIDByOrderWithBlock(ctx, tx, orderID)
+	if err != nil {
+		return fmt.Errorf("storage error of get user id by order, error:%w", err)
+	}
+	ctx = logging.SetUserID(ctx, userID)
+
+	err = j.createOrderEvent(ctx, tx, userID, orderID, order.StatusInvalid, 0)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage error of commit transaction, error:%w", err)
+	}
+
+	return nil
+}
+
+// createOrderEvent сохраняет событие смены статуса заказа для потока событий пользователя и вебхуков.
+func (j *job) createOrderEvent(ctx context.Context, tx pgx.Tx, userID, orderID int64, status string,
+	accrual float64) error {
+	payload := events.Order{
+		Number:  strconv.FormatInt(orderID, 10),
+		Status:  status,
+		Accrual: accrual,
+	}
+
+	err := j.storage.CreateUserEvent(ctx, tx, userID, events.OrderType(status), payload)
+	if err != nil {
+		return fmt.Errorf("storage error of create order event, error:%w", err)
+	}
+
+	err = j.storage.CreateWebhookDeliveries(ctx, tx, userID, events.OrderType(status), payload)
+	if err != nil {
+		return fmt.Errorf("storage error of create webhook deliveries, error:%w", err)
+	}
+
 	return nil
 }
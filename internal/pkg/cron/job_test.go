@@ -0,0 +1,142 @@
+package cron
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type commitTx struct {
+	pgx.Tx
+	committed bool
+}
+
+func (tx *commitTx) Commit(_ context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *commitTx) Rollback(_ context.Context) error {
+	return nil
+}
+
+// orderStorage хранилище с одним заказом в статусе status, запоминает обновления заказа, опросы и события.
+type orderStorage struct {
+	ports.UpdateOrderStorage
+	tx       *commitTx
+	status   string
+	updated  []string
+	polls    []string
+	events   []string
+	webhooks []string
+	balance  float64
+}
+
+func (s *orderStorage) BeginTx(_ context.Context) (pgx.Tx, error) {
+	return s.tx, nil
+}
+
+func (s *orderStorage) GetNotProcessedOrderWithBlock(_ context.Context, _ pgx.Tx) (int64, string, error) {
+	return 12345678903, s.status, nil
+}
+
+func (s *orderStorage) GetUserIDByOrderWithBlock(_ context.Context, _ pgx.Tx, _ int64) (int64, error) {
+	return 1, nil
+}
+
+func (s *orderStorage) GetBalanceWithBlock(_ context.Context, _ pgx.Tx, _ int64) (float64, error) {
+	return s.balance, nil
+}
+
+func (s *orderStorage) UpdateOrder(_ context.Context, _ pgx.Tx, _ int64, status string, _ float64) error {
+	s.updated = append(s.updated, status)
+	return nil
+}
+
+func (s *orderStorage) CreateOrderPoll(_ context.Context, _ pgx.Tx, _ int64, status string, _ float64) error {
+	s.polls = append(s.polls, status)
+	return nil
+}
+
+func (s *orderStorage) UpdateBalance(_ context.Context, _ pgx.Tx, _ int64, balance float64) error {
+	s.balance = balance
+	return nil
+}
+
+func (s *orderStorage) CreateUserEvent(_ context.Context, _ pgx.Tx, _ int64, eventType string, _ any) error {
+	s.events = append(s.events, eventType)
+	return nil
+}
+
+func (s *orderStorage) CreateWebhookDeliveries(_ context.Context, _ pgx.Tx, _ int64, eventType string,
+	_ any) error {
+	s.webhooks = append(s.webhooks, eventType)
+	return nil
+}
+
+type stubAccrual struct {
+	status  string
+	accrual float64
+}
+
+func (a stubAccrual) Get(_ context.Context, order string) (*ports.Accrual, error) {
+	return &ports.Accrual{Order: order, Status: a.status, Accrual: a.accrual}, nil
+}
+
+func TestJobRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   string
+		accrual  stubAccrual
+		updated  string
+		events   []string
+		webhooks []string
+		balance  float64
+	}{
+		{
+			name:     "Registered new order becomes processing",
+			status:   "NEW",
+			accrual:  stubAccrual{status: "REGISTERED"},
+			updated:  "PROCESSING",
+			events:   []string{"order.processing"},
+			webhooks: []string{"order.processing"},
+		},
+		{
+			name:    "Registered processing order has no event",
+			status:  "PROCESSING",
+			accrual: stubAccrual{status: "REGISTERED"},
+			updated: "PROCESSING",
+		},
+		{
+			name:     "Processed order accrues balance",
+			status:   "PROCESSING",
+			accrual:  stubAccrual{status: "PROCESSED", accrual: 500},
+			updated:  "PROCESSED",
+			events:   []string{"order.processed", "balance.updated"},
+			webhooks: []string{"order.processed"},
+			balance:  500,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &orderStorage{tx: &commitTx{}, status: test.status}
+			locked := make(chan error, 1)
+
+			err := NewJob(1, s, test.accrual, locked).Run(context.Background())
+			require.NoError(t, err)
+			require.NoError(t, <-locked)
+
+			assert.Equal(t, []string{test.updated}, s.updated)
+			assert.Equal(t, []string{test.accrual.status}, s.polls, "poll keeps status of accrual response")
+			assert.Equal(t, test.events, s.events)
+			assert.Equal(t, test.webhooks, s.webhooks)
+			assert.Equal(t, test.balance, s.balance)
+			assert.True(t, s.tx.committed)
+		})
+	}
+}
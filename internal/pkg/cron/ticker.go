@@ -2,30 +2,84 @@ package cron
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/k0st1a/gophermart/internal/adapters/api/accrual"
+	"github.com/k0st1a/gophermart/internal/pkg/tracing"
 	"github.com/k0st1a/gophermart/internal/ports"
 	"github.com/rs/zerolog/log"
 )
 
+// staleTicks число пропущенных интервалов опроса, после которого цикл опроса считается зависшим.
+const staleTicks = 10
+
+// minStaleAfter минимальное время без тиков, после которого цикл опроса считается зависшим.
+const minStaleAfter = 30 * time.Second
+
+// Settings настройки цикла опроса системы расчёта начислений, которые можно менять без перезапуска.
+type Settings struct {
+	// AccrualAddress адрес системы расчёта начислений.
+	AccrualAddress string
+	// Interval период запуска заданий опроса.
+	Interval time.Duration
+	// AccrualTimeout ограничение времени запроса к системе расчёта начислений.
+	AccrualTimeout time.Duration
+	// Concurrency число заданий опроса, запускаемых на каждом тике. Каждое задание опрашивает один заказ и
+	// держит соединение пула БД до конца опроса.
+	Concurrency int
+}
+
 type tick struct {
-	orderStorage   ports.UpdateOrderStorage
-	accrualAddress string
-	pollInterval   int
+	orderStorage ports.UpdateOrderStorage
+	settings     atomic.Pointer[Settings]
+	stop         chan struct{}
+	// updated сигнализирует Run о смене настроек, чтобы перезапустить таймер с новым интервалом.
+	updated  chan struct{}
+	lastTick atomic.Int64
+	stopOnce sync.Once
+}
+
+// NewTicker создаёт цикл опроса системы расчёта начислений с настройками s.
+func NewTicker(s Settings, storage ports.UpdateOrderStorage) *tick {
+	t := &tick{
+		orderStorage: storage,
+		stop:         make(chan struct{}),
+		updated:      make(chan struct{}, 1),
+	}
+	t.settings.Store(&s)
+	return t
 }
 
-func NewTicker(address string, interval int, storage ports.UpdateOrderStorage) *tick {
-	return &tick{
-		accrualAddress: address,
-		pollInterval:   interval,
-		orderStorage:   storage,
+// Update меняет настройки цикла опроса. Задания, уже начатые на текущем тике, доработают со старыми
+// настройками, новый интервал отсчитывается с момента вызова.
+func (t *tick) Update(s Settings) {
+	t.settings.Store(&s)
+	select {
+	case t.updated <- struct{}{}:
+	default:
 	}
 }
 
+// Stop прекращает опрос системы расчёта начислений: Run завершается после окончания текущих заданий.
+// Отмена контекста Run, в отличие от Stop, прерывает и текущие задания.
+func (t *tick) Stop() {
+	t.stopOnce.Do(func() {
+		close(t.stop)
+	})
+}
+
 func (t *tick) Run(ctx context.Context) error {
-	log.Printf("Run ticker")
-	ticker := time.NewTicker(time.Duration(t.pollInterval) * time.Second)
+	s := t.settings.Load()
+	log.Ctx(ctx).Info().
+		Dur("poll_interval", s.Interval).
+		Int("poll_concurrency", s.Concurrency).
+		Msg("run ticker")
+	t.lastTick.Store(time.Now().UnixNano())
+	ticker := time.NewTicker(s.Interval)
 
 	tick := 0
 
@@ -34,18 +88,83 @@ func (t *tick) Run(ctx context.Context) error {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Printf("Ticker closed with cause:%s", ctx.Err())
+			log.Ctx(ctx).Info().AnErr("cause", ctx.Err()).Msg("ticker closed")
+			ticker.Stop()
+			return nil
+		case <-t.stop:
+			log.Ctx(ctx).Info().Msg("ticker stopped")
 			ticker.Stop()
 			return nil
+		case <-t.updated:
+			s = t.settings.Load()
+			ticker.Reset(s.Interval)
+			log.Ctx(ctx).Info().
+				Dur("poll_interval", s.Interval).
+				Int("poll_concurrency", s.Concurrency).
+				Msg("ticker settings updated")
 		case <-ticker.C:
-			tick++
-			log.Printf("Got tick %d", tick)
-			a := accrual.NewClient(t.accrualAddress, block)
-			j := NewJob(tick, t.orderStorage, a)
-			err := j.Run(ctx)
-			if err != nil {
-				log.Error().Err(err).Msg("error of run job")
+			// Тик и Stop могли прийти одновременно: после Stop новые задания не начинаются.
+			select {
+			case <-t.stop:
+				continue
+			default:
 			}
+			t.lastTick.Store(time.Now().UnixNano())
+			s = t.settings.Load()
+			a := accrual.NewClient(s.AccrualAddress, s.AccrualTimeout, block)
+
+			var wg sync.WaitGroup
+			for i := 0; i < s.Concurrency; i++ {
+				tick++
+				log.Ctx(ctx).Debug().Int("tick", tick).Msg("got tick")
+				locked := make(chan error, 1)
+				wg.Add(1)
+				go func(number int) {
+					defer wg.Done()
+					t.runJob(ctx, number, a, locked)
+				}(tick)
+
+				// Следующее задание запускается, когда предыдущее заблокировало заказ. Если заказов для опроса
+				// не осталось, остальные задания тика не запускаются и не занимают соединения пула.
+				if err := <-locked; errors.Is(err, ports.ErrNoOrderToPoll) {
+					log.Ctx(ctx).Debug().Int("tick", tick).Msg("no order to poll")
+					break
+				}
+			}
+			wg.Wait()
 		}
 	}
 }
+
+// runJob выполняет задание опроса одного заказа с номером number.
+func (t *tick) runJob(ctx context.Context, number int, a ports.AccrualGetter, locked chan<- error) {
+	j := NewJob(number, t.orderStorage, a, locked)
+	jctx, span := tracing.Tracer().Start(ctx, "accrual.poll")
+	defer span.End()
+
+	err := j.Run(jctx)
+	if errors.Is(err, ports.ErrNoOrderToPoll) {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		log.Ctx(ctx).Error().Err(err).Int("job", number).Msg("error of run job")
+	}
+}
+
+// Check проверяет, что цикл опроса запущен и тикал недавно: не раньше staleTicks интервалов опроса назад,
+// но не менее minStaleAfter. Зависший на опросе системы расчёта начислений цикл не тикает.
+func (t *tick) Check(_ context.Context) error {
+	last := t.lastTick.Load()
+	if last == 0 {
+		return fmt.Errorf("ticker is not running")
+	}
+
+	staleAfter := max(minStaleAfter, staleTicks*t.settings.Load().Interval)
+	since := time.Since(time.Unix(0, last))
+	if since > staleAfter {
+		return fmt.Errorf("last tick was %s ago", since.Round(time.Second))
+	}
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package cron
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubTx struct {
+	pgx.Tx
+}
+
+func (stubTx) Rollback(_ context.Context) error {
+	return nil
+}
+
+// stubStorage хранилище без заказов для опроса, считает начатые задания.
+type stubStorage struct {
+	ports.UpdateOrderStorage
+	// onBegin вызывается при начале каждого задания.
+	onBegin func()
+	jobs    atomic.Int64
+}
+
+func (s *stubStorage) BeginTx(_ context.Context) (pgx.Tx, error) {
+	s.jobs.Add(1)
+	if s.onBegin != nil {
+		s.onBegin()
+	}
+	return stubTx{}, nil
+}
+
+func (s *stubStorage) GetNotProcessedOrderWithBlock(_ context.Context, _ pgx.Tx) (int64, string, error) {
+	return 0, "", ports.ErrNoOrderToPoll
+}
+
+// runTicker запускает t и возвращает канал, который закрывается по завершении Run.
+func runTicker(t *testing.T, tk *tick) <-chan struct{} {
+	t.Helper()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, tk.Run(context.Background()))
+	}()
+	t.Cleanup(func() {
+		tk.Stop()
+		<-done
+	})
+
+	return done
+}
+
+func TestTickerUpdate(t *testing.T) {
+	s := &stubStorage{}
+	tk := NewTicker(Settings{Interval: time.Hour, Concurrency: 1}, s)
+	runTicker(t, tk)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Zero(t, s.jobs.Load(), "no ticks with initial interval")
+
+	tk.Update(Settings{Interval: 10 * time.Millisecond, Concurrency: 1})
+	require.Eventually(t, func() bool {
+		return s.jobs.Load() >= 2
+	}, time.Second, 5*time.Millisecond, "ticks with updated interval")
+}
+
+func TestTickerNoOrderStopsTick(t *testing.T) {
+	s := &stubStorage{}
+	tk := NewTicker(Settings{Interval: 10 * time.Millisecond, Concurrency: 5}, s)
+	s.onBegin = tk.Stop
+	done := runTicker(t, tk)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after Stop")
+	}
+
+	// Задание первого тика не нашло заказа, остальные задания тика не запускаются.
+	assert.Equal(t, int64(1), s.jobs.Load())
+}
+
+func TestTickerStop(t *testing.T) {
+	tk := NewTicker(Settings{Interval: time.Hour, Concurrency: 1}, &stubStorage{})
+	done := runTicker(t, tk)
+
+	tk.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after Stop")
+	}
+}
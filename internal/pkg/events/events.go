@@ -0,0 +1,163 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	TypeBalanceUpdated    = "balance.updated"
+	TypeWithdrawalCreated = "withdrawal.created"
+)
+
+// OrderType возвращает тип события смены статуса заказа, например order.processed.
+func OrderType(status string) string {
+	return "order." + strings.ToLower(status)
+}
+
+type Order struct {
+	Number  string  `json:"number"`
+	Status  string  `json:"status"`
+	Accrual float64 `json:"accrual,omitempty"`
+}
+
+type Balance struct {
+	Current float64 `json:"current"`
+}
+
+type Withdrawal struct {
+	Order string  `json:"order"`
+	Sum   float64 `json:"sum"`
+}
+
+type Managment interface {
+	// Subscribe возвращает канал, в который приходит сигнал при появлении новых событий пользователя,
+	// и функцию отмены подписки.
+	Subscribe(userID int64) (<-chan struct{}, func())
+	List(ctx context.Context, userID, afterID int64, limit int) ([]Event, error)
+	LastID(ctx context.Context, userID int64) (int64, error)
+}
+
+type Event struct {
+	Type string
+	Data []byte
+	ID   int64
+}
+
+// reconnectDelay задержка перед повторной подпиской после потери соединения с БД.
+const reconnectDelay = time.Second
+
+type broker struct {
+	storage     ports.EventStorage
+	subscribers map[int64]map[chan struct{}]struct{}
+	mu          sync.Mutex
+}
+
+func New(storage ports.EventStorage) *broker {
+	return &broker{
+		storage:     storage,
+		subscribers: make(map[int64]map[chan struct{}]struct{}),
+	}
+}
+
+// Run слушает уведомления БД о новых событиях и будит подписчиков. Блокируется до отмены ctx.
+func (b *broker) Run(ctx context.Context) error {
+	for {
+		err := b.storage.ListenUserEvents(ctx, b.notify)
+		if ctx.Err() != nil {
+			return nil
+		}
+		log.Ctx(ctx).Error().Err(err).Msg("error of listen user events")
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+
+		// Пока соединения не было, уведомления могли потеряться: подписчики сами перечитают события.
+		b.notifyAll()
+	}
+}
+
+func (b *broker) Subscribe(userID int64) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	b.mu.Lock()
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[chan struct{}]struct{})
+	}
+	b.subscribers[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscribers[userID], ch)
+		if len(b.subscribers[userID]) == 0 {
+			delete(b.subscribers, userID)
+		}
+	}
+}
+
+func (b *broker) List(ctx context.Context, userID, afterID int64, limit int) ([]Event, error) {
+	dbEvents, err := b.storage.GetUserEvents(ctx, userID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("storage error of get user events:%w", err)
+	}
+
+	events := make([]Event, 0, len(dbEvents))
+	for _, e := range dbEvents {
+		events = append(events, Event{
+			ID:   e.ID,
+			Type: e.Type,
+			Data: e.Data,
+		})
+	}
+
+	return events, nil
+}
+
+func (b *broker) LastID(ctx context.Context, userID int64) (int64, error) {
+	id, err := b.storage.GetLastUserEventID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("storage error of get last user event id:%w", err)
+	}
+
+	return id, nil
+}
+
+func (b *broker) notify(userID int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[userID] {
+		wake(ch)
+	}
+}
+
+func (b *broker) notifyAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, chs := range b.subscribers {
+		for ch := range chs {
+			wake(ch)
+		}
+	}
+}
+
+// wake не блокируется: если сигнал уже ожидает обработки, новый не нужен.
+func wake(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
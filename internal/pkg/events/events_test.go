@@ -0,0 +1,115 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signaled(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestSubscribeNotify(t *testing.T) {
+	tests := []struct {
+		name   string
+		notify []int64
+		want1  bool
+		want2  bool
+	}{
+		{name: "No notifications"},
+		{name: "Notify subscriber", notify: []int64{1}, want1: true},
+		{name: "Notify other user", notify: []int64{2}, want2: true},
+		{name: "Repeated notifications are coalesced", notify: []int64{1, 1, 1}, want1: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := New(nil)
+			ch1, cancel1 := b.Subscribe(1)
+			defer cancel1()
+			ch2, cancel2 := b.Subscribe(2)
+			defer cancel2()
+
+			for _, userID := range test.notify {
+				b.notify(userID)
+			}
+
+			assert.Equal(t, test.want1, signaled(ch1))
+			assert.Equal(t, test.want2, signaled(ch2))
+			assert.False(t, signaled(ch1), "signal must be delivered once")
+		})
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	b := New(nil)
+	ch1, cancel1 := b.Subscribe(1)
+	ch2, cancel2 := b.Subscribe(1)
+	defer cancel2()
+
+	cancel1()
+	b.notify(1)
+
+	assert.False(t, signaled(ch1))
+	assert.True(t, signaled(ch2))
+
+	cancel2()
+	assert.Empty(t, b.subscribers)
+}
+
+type stubStorage struct {
+	ports.EventStorage
+	listen func(ctx context.Context, notify func(userID int64)) error
+}
+
+func (s *stubStorage) ListenUserEvents(ctx context.Context, notify func(userID int64)) error {
+	return s.listen(ctx, notify)
+}
+
+func TestRunNotifiesAllAfterReconnect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	calls := 0
+	storage := &stubStorage{
+		listen: func(ctx context.Context, _ func(userID int64)) error {
+			calls++
+			if calls == 1 {
+				return errors.New("connection lost")
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+
+	b := New(storage)
+	ch1, cancel1 := b.Subscribe(1)
+	defer cancel1()
+	ch2, cancel2 := b.Subscribe(2)
+	defer cancel2()
+
+	done := make(chan error, 1)
+	go func() { done <- b.Run(ctx) }()
+
+	for _, ch := range []<-chan struct{}{ch1, ch2} {
+		select {
+		case <-ch:
+		case <-time.After(5 * reconnectDelay):
+			require.Fail(t, "subscriber was not notified after reconnect")
+		}
+	}
+
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,103 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/pkg/order"
+	"github.com/k0st1a/gophermart/internal/pkg/withdraw"
+	"github.com/k0st1a/gophermart/internal/ports"
+)
+
+type Managment interface {
+	Export(ctx context.Context, userID int64) (*Archive, error)
+}
+
+// Archive содержит все персональные данные пользователя, которые хранит гофермарт.
+//
+//nolint:govet //incorrectly detects alignment
+type Archive struct {
+	ExportedAt     time.Time
+	Login          string
+	Role           string
+	Balance        float64
+	Withdrawn      float64
+	Orders         []order.Order
+	Withdrawals    []withdraw.Withdraw
+	BalanceHistory []BalanceEntry
+}
+
+//nolint:govet //incorrectly detects alignment
+type BalanceEntry struct {
+	At     time.Time
+	Type   string
+	Reason string
+	Order  int64
+	Amount float64
+}
+
+var ErrUserNotFound = errors.New("user not found")
+
+type export struct {
+	storage  ports.ExportStorage
+	order    order.Managment
+	withdraw withdraw.Managment
+}
+
+func New(storage ports.ExportStorage, o order.Managment, w withdraw.Managment) Managment {
+	return &export{
+		storage:  storage,
+		order:    o,
+		withdraw: w,
+	}
+}
+
+func (e *export) Export(ctx context.Context, userID int64) (*Archive, error) {
+	u, err := e.storage.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ports.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+
+		return nil, fmt.Errorf("storage error of get user by id:%w", err)
+	}
+
+	orders, err := e.order.List(ctx, userID, ports.ListFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("error of get orders:%w", err)
+	}
+
+	withdrawals, err := e.withdraw.List(ctx, userID, ports.ListFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("error of get withdrawals:%w", err)
+	}
+
+	dbHistory, err := e.storage.GetBalanceHistory(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("storage error of get balance history:%w", err)
+	}
+
+	history := make([]BalanceEntry, 0, len(dbHistory))
+	for _, h := range dbHistory {
+		history = append(history, BalanceEntry{
+			At:     h.At,
+			Type:   h.Type,
+			Reason: h.Reason,
+			Order:  h.Order.Int64,
+			Amount: h.Amount,
+		})
+	}
+
+	return &Archive{
+		ExportedAt:     time.Now(),
+		Login:          u.Login,
+		Role:           u.Role,
+		Balance:        u.Balance,
+		Withdrawn:      u.Withdrawn,
+		Orders:         orders,
+		Withdrawals:    withdrawals,
+		BalanceHistory: history,
+	}, nil
+}
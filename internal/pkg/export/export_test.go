@@ -0,0 +1,88 @@
+package export
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/pkg/order"
+	"github.com/k0st1a/gophermart/internal/pkg/withdraw"
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubStorage struct {
+	users   map[int64]*ports.User
+	history []ports.BalanceEntry
+}
+
+func (s *stubStorage) GetUserByID(_ context.Context, userID int64) (*ports.User, error) {
+	u, ok := s.users[userID]
+	if !ok {
+		return nil, ports.ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (s *stubStorage) GetBalanceHistory(_ context.Context, _ int64) ([]ports.BalanceEntry, error) {
+	return s.history, nil
+}
+
+type stubOrder struct {
+	order.Managment
+	orders []order.Order
+}
+
+// List проверяет, что в выгрузку попадают все заказы, без ограничения страницы.
+func (s stubOrder) List(_ context.Context, _ int64, filter ports.ListFilter) ([]order.Order, error) {
+	if filter.Limit != 0 {
+		return s.orders[:filter.Limit], nil
+	}
+	return s.orders, nil
+}
+
+type stubWithdraw struct {
+	withdraw.Managment
+	withdrawals []withdraw.Withdraw
+}
+
+func (s stubWithdraw) List(_ context.Context, _ int64, filter ports.ListFilter) ([]withdraw.Withdraw, error) {
+	if filter.Limit != 0 {
+		return s.withdrawals[:filter.Limit], nil
+	}
+	return s.withdrawals, nil
+}
+
+func TestExport(t *testing.T) {
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := &stubStorage{
+		users: map[int64]*ports.User{1: {ID: 1, Login: "alice", Role: "user", Balance: 400, Withdrawn: 100}},
+		history: []ports.BalanceEntry{
+			{At: at, Type: "accrual", Order: sql.NullInt64{Int64: 12345678903, Valid: true}, Amount: 500},
+			{At: at, Type: "adjustment", Reason: "compensation", Amount: 50},
+		},
+	}
+	orders := []order.Order{{Number: 12345678903, Status: "PROCESSED", Accrual: 500}, {Number: 9278923470}}
+	withdrawals := []withdraw.Withdraw{{Order: 2377225624, Sum: 100}, {Order: 346436439, Sum: 50}}
+
+	e := New(s, stubOrder{orders: orders}, stubWithdraw{withdrawals: withdrawals})
+
+	a, err := e.Export(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", a.Login)
+	assert.Equal(t, "user", a.Role)
+	assert.Equal(t, 400.0, a.Balance)
+	assert.Equal(t, 100.0, a.Withdrawn)
+	assert.Equal(t, orders, a.Orders)
+	assert.Equal(t, withdrawals, a.Withdrawals)
+	assert.Equal(t, []BalanceEntry{
+		{At: at, Type: "accrual", Order: 12345678903, Amount: 500},
+		{At: at, Type: "adjustment", Reason: "compensation", Amount: 50},
+	}, a.BalanceHistory)
+	assert.False(t, a.ExportedAt.IsZero())
+
+	_, err = e.Export(context.Background(), 2)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
@@ -0,0 +1,92 @@
+// Package health собирает проверки готовности сервиса к обработке запросов.
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrShuttingDown возвращается проверкой shutdown после начала остановки сервиса.
+var ErrShuttingDown = errors.New("shutting down")
+
+// Check проверка компонента, nil - компонент готов.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	check Check
+	name  string
+}
+
+// Health выполняет зарегистрированные проверки готовности. После вызова Shutdown сервис считается
+// неготовым независимо от результатов проверок.
+type Health struct {
+	checks       []namedCheck
+	mu           sync.RWMutex
+	shuttingDown atomic.Bool
+}
+
+// Result результат проверки готовности: ошибки по именам проверок, nil - проверка пройдена.
+type Result struct {
+	Checks map[string]error
+	Ready  bool
+}
+
+func New() *Health {
+	return &Health{}
+}
+
+// Add регистрирует проверку готовности name.
+func (h *Health) Add(name string, c Check) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.checks = append(h.checks, namedCheck{name: name, check: c})
+}
+
+// Shutdown помечает сервис как останавливающийся.
+func (h *Health) Shutdown() {
+	h.shuttingDown.Store(true)
+}
+
+// Ready выполняет все проверки параллельно.
+func (h *Health) Ready(ctx context.Context) Result {
+	h.mu.RLock()
+	checks := h.checks
+	h.mu.RUnlock()
+
+	r := Result{
+		Checks: make(map[string]error, len(checks)+1),
+		Ready:  true,
+	}
+
+	var shutdownErr error
+	if h.shuttingDown.Load() {
+		shutdownErr = ErrShuttingDown
+		r.Ready = false
+	}
+	r.Checks["shutdown"] = shutdownErr
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, c := range checks {
+		wg.Add(1)
+		go func(c namedCheck) {
+			defer wg.Done()
+			err := c.check(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			r.Checks[c.name] = err
+			if err != nil {
+				r.Ready = false
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return r
+}
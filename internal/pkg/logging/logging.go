@@ -0,0 +1,95 @@
+// Package logging настраивает глобальный логгер zerolog: уровень, формат вывода и маскирование секретов,
+// а также хранит в контексте логгер с полями запроса (идентификатор запроса, пользователь).
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Форматы вывода логов, значения переменной окружения LOG_FORMAT.
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+)
+
+// Имена полей, которые добавляются к логгеру запроса.
+const (
+	FieldRequestID = "request_id"
+	FieldUserID    = "user_id"
+)
+
+// FieldTargetUserID имя поля пользователя, над данными которого выполняется операция с БД. Он может
+// отличаться от аутентифицированного пользователя user_id, например при вызовах администратора или
+// служебных команд, где user_id нет.
+const FieldTargetUserID = "target_user_id"
+
+func init() {
+	// log.Ctx для контекста без логгера возвращает глобальный логгер, а не отключённый.
+	zerolog.DefaultContextLogger = &log.Logger
+}
+
+// Setup устанавливает глобальный логгер с уровнем level (trace, debug, info, warn, error) и форматом
+// format, пишущий в stderr. Значения полей с секретами маскируются, см. NewRedactWriter.
+func Setup(level, format string) error {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level:%q", level)
+	}
+
+	var out io.Writer
+	switch format {
+	case "", FormatJSON:
+		out = os.Stderr
+	case FormatConsole:
+		out = zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}
+	default:
+		return fmt.Errorf("invalid log format:%q", format)
+	}
+
+	zerolog.SetGlobalLevel(lvl)
+	log.Logger = zerolog.New(NewRedactWriter(out)).With().Timestamp().Logger()
+
+	return nil
+}
+
+// SetLevel меняет уровень логирования.
+func SetLevel(level string) error {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level:%q", level)
+	}
+
+	zerolog.SetGlobalLevel(lvl)
+	return nil
+}
+
+// WithRequestID возвращает контекст с собственным логгером запроса, в записи которого добавляется
+// идентификатор запроса.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	l := log.Ctx(ctx).With().Str(FieldRequestID, requestID).Logger()
+	return l.WithContext(ctx)
+}
+
+// SetUserID добавляет идентификатор пользователя к логгеру запроса из ctx. Логгер запроса изменяется
+// на месте, поэтому идентификатор попадает и в записи, которые сделаны выше по цепочке middleware после
+// обработки запроса, например, в журнал запросов. Если логгер запроса не добавлен в ctx, возвращается
+// контекст с новым логгером.
+func SetUserID(ctx context.Context, userID int64) context.Context {
+	l := zerolog.Ctx(ctx)
+	if l == zerolog.DefaultContextLogger {
+		nl := log.Ctx(ctx).With().Int64(FieldUserID, userID).Logger()
+		return nl.WithContext(ctx)
+	}
+
+	l.UpdateContext(func(c zerolog.Context) zerolog.Context {
+		return c.Int64(FieldUserID, userID)
+	})
+	return ctx
+}
@@ -0,0 +1,103 @@
+// Package metrics содержит метрики Prometheus гофермарта. Метрики регистрируются в реестре вызовом Register.
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "gophermart"
+
+// Исходы запроса к системе расчёта начислений, значения метки outcome метрики AccrualRequests.
+const (
+	AccrualOutcomeOK              = "200"
+	AccrualOutcomeNotRegistered   = "204"
+	AccrualOutcomeTooManyRequests = "429"
+	AccrualOutcomeServerError     = "5xx"
+	AccrualOutcomeOther           = "other"
+	AccrualOutcomeError           = "error"
+)
+
+var (
+	// HTTPRequests число обработанных HTTP запросов по шаблону маршрута chi.
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration время обработки HTTP запросов по шаблону маршрута chi.
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by method and route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// AccrualRequests число запросов к системе расчёта начислений по исходу.
+	AccrualRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "accrual",
+		Name:      "requests_total",
+		Help:      "Number of accrual system requests by outcome: 200, 204, 429, 5xx, other or error.",
+	}, []string{"outcome"})
+
+	// PointsAccrued сумма начисленных баллов.
+	PointsAccrued = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "points_accrued_total",
+		Help:      "Total points accrued to users.",
+	})
+
+	// PointsWithdrawn сумма списанных баллов.
+	PointsWithdrawn = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "points_withdrawn_total",
+		Help:      "Total points withdrawn by users.",
+	})
+)
+
+// AccrualOutcome возвращает значение метки outcome для кода ответа системы расчёта начислений.
+func AccrualOutcome(statusCode int) string {
+	switch {
+	case statusCode == http.StatusOK:
+		return AccrualOutcomeOK
+	case statusCode == http.StatusNoContent:
+		return AccrualOutcomeNotRegistered
+	case statusCode == http.StatusTooManyRequests:
+		return AccrualOutcomeTooManyRequests
+	case statusCode >= http.StatusInternalServerError:
+		return AccrualOutcomeServerError
+	default:
+		return AccrualOutcomeOther
+	}
+}
+
+// Register регистрирует метрики гофермарта, метрики среды выполнения Go и процесса, а также дополнительные
+// коллекторы, например статистику пула соединений с БД.
+func Register(reg prometheus.Registerer, extra ...prometheus.Collector) error {
+	cs := []prometheus.Collector{
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		AccrualRequests,
+		PointsAccrued,
+		PointsWithdrawn,
+	}
+	cs = append(cs, extra...)
+
+	for _, c := range cs {
+		err := reg.Register(c)
+		if err != nil {
+			return fmt.Errorf("failed to register metrics collector:%w", err)
+		}
+	}
+
+	return nil
+}
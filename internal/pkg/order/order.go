@@ -7,11 +7,15 @@ import (
 	"time"
 
 	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/rs/zerolog/log"
 )
 
 type Managment interface {
 	Create(ctx context.Context, userID, orderID int64) error
-	List(ctx context.Context, userID int64) ([]Order, error)
+	List(ctx context.Context, userID int64, filter ports.ListFilter) ([]Order, error)
+	Get(ctx context.Context, userID, orderID int64) (*Details, error)
+	CreateBatch(ctx context.Context, userID int64, orderIDs []int64) (map[int64]error, error)
+	Requeue(ctx context.Context, orderID int64) error
 }
 
 type Order struct {
@@ -21,9 +25,34 @@ type Order struct {
 	Accrual    float64
 }
 
+// Details заказ вместе с историей опроса системы расчёта начислений.
+type Details struct {
+	Order
+	Polls []Poll
+}
+
+type Poll struct {
+	PolledAt time.Time
+	Status   string
+	Accrual  float64
+}
+
+// Статусы заказа гофермарта, совпадают со значениями типа status_type в БД.
+const (
+	StatusNew        = "NEW"
+	StatusProcessing = "PROCESSING"
+	StatusInvalid    = "INVALID"
+	StatusProcessed  = "PROCESSED"
+)
+
+// Statuses все статусы заказа, по которым можно отфильтровать список заказов.
+var Statuses = []string{StatusNew, StatusProcessing, StatusInvalid, StatusProcessed}
+
 var (
+	ErrNotFound                     = errors.New("order not found")
 	ErrAlreadyUploadedByAnotherUser = errors.New("order already uploaded by another user")
 	ErrAlreadyUploadedByThisUser    = errors.New("order already uploaded by this user")
+	ErrNotInvalid                   = errors.New("only INVALID order can be requeued")
 )
 
 type order struct {
@@ -62,10 +91,10 @@ func (o *order) create(ctx context.Context, userID, orderID int64) error {
 	return nil
 }
 
-func (o *order) List(ctx context.Context, userID int64) ([]Order, error) {
+func (o *order) List(ctx context.Context, userID int64, filter ports.ListFilter) ([]Order, error) {
 	orders := []Order{}
 
-	dbOrders, err := o.storage.GetOrders(ctx, userID)
+	dbOrders, err := o.storage.GetOrders(ctx, userID, filter)
 	if err != nil {
 		return orders, fmt.Errorf("error of get orders from storage:%w", err)
 	}
@@ -86,3 +115,138 @@ func (o *order) List(ctx context.Context, userID int64) ([]Order, error) {
 
 	return orders, nil
 }
+
+// Get возвращает заказ пользователя. Для чужого заказа, как и для несуществующего, возвращается ErrNotFound,
+// чтобы не раскрывать факт его существования.
+func (o *order) Get(ctx context.Context, userID, orderID int64) (*Details, error) {
+	dbUserID, err := o.storage.GetUserIDByOrder(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, ports.ErrOrderNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("error of get order:%w", err)
+	}
+
+	if dbUserID != userID {
+		return nil, ErrNotFound
+	}
+
+	dbOrder, err := o.storage.GetOrder(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, ports.ErrOrderNotFound) {
+			return nil, ErrNotFound
+		}
+
+		return nil, fmt.Errorf("error of get order:%w", err)
+	}
+
+	dbPolls, err := o.storage.GetOrderPolls(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("error of get order polls:%w", err)
+	}
+
+	d := &Details{
+		Order: Order{
+			Number:     dbOrder.Number,
+			Status:     dbOrder.Status,
+			Accrual:    dbOrder.Accrual.Float64,
+			UploadedAt: dbOrder.UploadedAt.Truncate(time.Second),
+		},
+		Polls: make([]Poll, 0, len(dbPolls)),
+	}
+
+	for _, p := range dbPolls {
+		d.Polls = append(d.Polls, Poll{
+			PolledAt: p.PolledAt.Truncate(time.Second),
+			Status:   p.Status,
+			Accrual:  p.Accrual.Float64,
+		})
+	}
+
+	return d, nil
+}
+
+// CreateBatch создаёт заказы пакетом в одной транзакции. Для каждого номера возвращается результат:
+// nil, если заказ принят, ErrAlreadyUploadedByThisUser или ErrAlreadyUploadedByAnotherUser.
+func (o *order) CreateBatch(ctx context.Context, userID int64, orderIDs []int64) (map[int64]error, error) {
+	results := make(map[int64]error, len(orderIDs))
+
+	tx, err := o.storage.BeginTx(ctx)
+	if err != nil {
+		return results, fmt.Errorf("storage error of begin transaction:%w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	created, err := o.storage.CreateOrders(ctx, tx, userID, orderIDs)
+	if err != nil {
+		return results, fmt.Errorf("storage error of create orders:%w", err)
+	}
+
+	for _, id := range created {
+		results[id] = nil
+	}
+
+	var existing []int64
+	for _, id := range orderIDs {
+		if _, ok := results[id]; !ok {
+			existing = append(existing, id)
+		}
+	}
+
+	if len(existing) != 0 {
+		owners, err := o.storage.GetUserIDsByOrders(ctx, tx, existing)
+		if err != nil {
+			return results, fmt.Errorf("storage error of get user ids by orders:%w", err)
+		}
+
+		for _, id := range existing {
+			if owners[id] == userID {
+				results[id] = ErrAlreadyUploadedByThisUser
+			} else {
+				results[id] = ErrAlreadyUploadedByAnotherUser
+			}
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return results, fmt.Errorf("storage error of commit transaction:%w", err)
+	}
+
+	return results, nil
+}
+
+// Requeue возвращает заказ в статусе INVALID в очередь опроса системы расчёта начислений, например если
+// заказ был признан недействительным из-за сбоя системы расчёта начислений. Заказы в других статусах
+// не возвращаются: PROCESSED уже начислен, NEW и PROCESSING и так ожидают опроса.
+func (o *order) Requeue(ctx context.Context, orderID int64) error {
+	log.Ctx(ctx).Info().Int64("order_id", orderID).Msg("requeue order")
+
+	dbOrder, err := o.storage.GetOrder(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, ports.ErrOrderNotFound) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("error of get order:%w", err)
+	}
+
+	if dbOrder.Status != StatusInvalid {
+		return ErrNotInvalid
+	}
+
+	err = o.storage.RequeueOrder(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, ports.ErrOrderNotFound) {
+			// Статус заказа успел измениться.
+			return ErrNotInvalid
+		}
+
+		return fmt.Errorf("error of requeue order:%w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package order
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubStorage struct {
+	ports.OrderStorage
+	owners map[int64]int64
+	orders map[int64]*ports.Order
+	polls  map[int64][]ports.OrderPoll
+}
+
+func (s *stubStorage) GetUserIDByOrder(_ context.Context, orderID int64) (int64, error) {
+	userID, ok := s.owners[orderID]
+	if !ok {
+		return 0, ports.ErrOrderNotFound
+	}
+	return userID, nil
+}
+
+func (s *stubStorage) GetOrder(_ context.Context, orderID int64) (*ports.Order, error) {
+	return s.orders[orderID], nil
+}
+
+func (s *stubStorage) GetOrderPolls(_ context.Context, orderID int64) ([]ports.OrderPoll, error) {
+	return s.polls[orderID], nil
+}
+
+func TestGet(t *testing.T) {
+	uploaded := time.Date(2024, 1, 1, 10, 0, 0, 500, time.UTC)
+	polled := time.Date(2024, 1, 1, 10, 0, 5, 500, time.UTC)
+
+	s := &stubStorage{
+		owners: map[int64]int64{12345678903: 1, 9278923470: 2},
+		orders: map[int64]*ports.Order{
+			12345678903: {
+				Number:     12345678903,
+				Status:     "PROCESSED",
+				Accrual:    sql.NullFloat64{Float64: 500, Valid: true},
+				UploadedAt: uploaded,
+			},
+		},
+		polls: map[int64][]ports.OrderPoll{
+			12345678903: {
+				{PolledAt: polled, Status: "PROCESSING"},
+				{PolledAt: polled, Status: "PROCESSED", Accrual: sql.NullFloat64{Float64: 500, Valid: true}},
+			},
+		},
+	}
+	o := New(s)
+
+	tests := []struct {
+		name    string
+		orderID int64
+		want    *Details
+		err     error
+	}{
+		{
+			name:    "Own order with polls",
+			orderID: 12345678903,
+			want: &Details{
+				Order: Order{
+					Number:     12345678903,
+					Status:     "PROCESSED",
+					Accrual:    500,
+					UploadedAt: uploaded.Truncate(time.Second),
+				},
+				Polls: []Poll{
+					{PolledAt: polled.Truncate(time.Second), Status: "PROCESSING"},
+					{PolledAt: polled.Truncate(time.Second), Status: "PROCESSED", Accrual: 500},
+				},
+			},
+		},
+		// Чужой заказ неотличим от несуществующего, чтобы не раскрывать номера заказов других пользователей.
+		{name: "Order of other user", orderID: 9278923470, err: ErrNotFound},
+		{name: "Unknown order", orderID: 346436439, err: ErrNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := o.Get(context.Background(), 1, test.orderID)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.want, d)
+		})
+	}
+}
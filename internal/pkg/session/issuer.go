@@ -0,0 +1,88 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/k0st1a/gophermart/internal/pkg/auth"
+	"github.com/k0st1a/gophermart/internal/pkg/tracing"
+	"github.com/k0st1a/gophermart/internal/pkg/user"
+)
+
+// ErrInvalidCredentials неверный логин или пароль. Какой именно из них неверен, не раскрывается.
+var ErrInvalidCredentials = errors.New("invalid login or password")
+
+// Client клиент, которому выдаётся токен: сохраняется в сессии для списка активных сессий.
+type Client struct {
+	UserAgent string
+	IP        string
+}
+
+// Issuer выдаёт токены доступа. Используется и REST, и gRPC API, чтобы вход по паролю и создание сессии
+// не расходились между транспортами.
+type Issuer interface {
+	// Login проверяет логин и пароль и выдаёт токен новой сессии. Возвращает ErrInvalidCredentials
+	// и user.ErrLocked, о блокировке сообщается только после проверки пароля.
+	Login(ctx context.Context, login, password string, c Client) (string, error)
+	// Issue начинает новую сессию пользователя и выдаёт токен с его текущей ролью, привязанный к сессии.
+	Issue(ctx context.Context, userID int64, c Client) (string, error)
+}
+
+type issuer struct {
+	auth    auth.UserAuthentication
+	user    user.Managment
+	session Managment
+}
+
+func NewIssuer(a auth.UserAuthentication, u user.Managment, s Managment) Issuer {
+	return &issuer{
+		auth:    a,
+		user:    u,
+		session: s,
+	}
+}
+
+func (i *issuer) Login(ctx context.Context, login, password string, c Client) (string, error) {
+	userID, hash, locked, err := i.user.GetIDAndPassword(ctx, login)
+	if err != nil {
+		if errors.Is(err, user.ErrNotFound) {
+			return "", ErrInvalidCredentials
+		}
+
+		return "", fmt.Errorf("error of get user id and password:%w", err)
+	}
+
+	_, span := tracing.Tracer().Start(ctx, "bcrypt.compare")
+	err = i.auth.CheckPasswordHash(password, hash)
+	span.End()
+	if err != nil {
+		return "", ErrInvalidCredentials
+	}
+
+	// О блокировке сообщается только после проверки пароля, иначе она раскрывалась бы по одному логину.
+	if locked {
+		return "", user.ErrLocked
+	}
+
+	return i.Issue(ctx, userID, c)
+}
+
+func (i *issuer) Issue(ctx context.Context, userID int64, c Client) (string, error) {
+	role, err := i.user.GetRole(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("error of get user role:%w", err)
+	}
+
+	sessionID, err := i.session.Create(ctx, userID, c.UserAgent, c.IP)
+	if err != nil {
+		return "", fmt.Errorf("error of create session:%w", err)
+	}
+
+	t, err := i.auth.GenerateToken(userID, sessionID, role)
+	if err != nil {
+		return "", fmt.Errorf("error of generate token:%w", err)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,106 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/k0st1a/gophermart/internal/pkg/auth"
+	"github.com/k0st1a/gophermart/internal/pkg/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubUser пользователи alice (роль admin) и заблокированный bob, пароль у обоих "secret".
+type stubUser struct {
+	user.Managment
+}
+
+func (stubUser) GetIDAndPassword(_ context.Context, login string) (int64, string, bool, error) {
+	switch login {
+	case "alice":
+		return 1, "hash:secret", false, nil
+	case "bob":
+		return 2, "hash:secret", true, nil
+	default:
+		return 0, "", false, user.ErrNotFound
+	}
+}
+
+func (stubUser) GetRole(_ context.Context, userID int64) (string, error) {
+	if userID == 1 {
+		return auth.RoleAdmin, nil
+	}
+	return auth.RoleUser, nil
+}
+
+type stubAuth struct {
+	auth.UserAuthentication
+}
+
+func (stubAuth) CheckPasswordHash(password, hash string) error {
+	if "hash:"+password != hash {
+		return errors.New("password mismatch")
+	}
+	return nil
+}
+
+func (stubAuth) GenerateToken(userID, sessionID int64, role string) (string, error) {
+	return fmt.Sprintf("%d/%d/%s", userID, sessionID, role), nil
+}
+
+// stubCreate запоминает клиентов созданных сессий.
+type stubCreate struct {
+	Managment
+	clients []Client
+}
+
+func (s *stubCreate) Create(_ context.Context, _ int64, userAgent, ip string) (int64, error) {
+	s.clients = append(s.clients, Client{UserAgent: userAgent, IP: ip})
+	return int64(len(s.clients)), nil
+}
+
+func TestLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		login    string
+		password string
+		token    string
+		err      error
+	}{
+		{name: "Success", login: "alice", password: "secret", token: "1/1/admin"},
+		{name: "Wrong password", login: "alice", password: "wrong", err: ErrInvalidCredentials},
+		{name: "Unknown login", login: "carol", password: "secret", err: ErrInvalidCredentials},
+		{name: "Locked with wrong password", login: "bob", password: "wrong", err: ErrInvalidCredentials},
+		{name: "Locked", login: "bob", password: "secret", err: user.ErrLocked},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &stubCreate{}
+			i := NewIssuer(stubAuth{}, stubUser{}, s)
+			c := Client{UserAgent: "curl/8.0", IP: "192.0.2.1"}
+
+			token, err := i.Login(context.Background(), test.login, test.password, c)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				assert.Empty(t, s.clients, "session must not be created")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.token, token)
+			assert.Equal(t, []Client{c}, s.clients)
+		})
+	}
+}
+
+func TestIssue(t *testing.T) {
+	s := &stubCreate{}
+	i := NewIssuer(stubAuth{}, stubUser{}, s)
+
+	token, err := i.Issue(context.Background(), 2, Client{})
+	require.NoError(t, err)
+	assert.Equal(t, "2/1/user", token)
+}
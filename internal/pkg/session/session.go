@@ -0,0 +1,100 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/ports"
+)
+
+type Managment interface {
+	Create(ctx context.Context, userID int64, userAgent, ip string) (int64, error)
+	Check(ctx context.Context, userID, sessionID int64) error
+	List(ctx context.Context, userID int64) ([]Session, error)
+	Terminate(ctx context.Context, userID, sessionID int64) error
+}
+
+type Session struct {
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	UserAgent  string
+	IP         string
+	ID         int64
+}
+
+var ErrNotFound = errors.New("session not found")
+
+// touchInterval ограничивает частоту обновления last_seen_at, чтобы не писать в БД на каждый запрос.
+const touchInterval = time.Minute
+
+type session struct {
+	storage ports.SessionStorage
+}
+
+func New(storage ports.SessionStorage) Managment {
+	return &session{
+		storage: storage,
+	}
+}
+
+func (s *session) Create(ctx context.Context, userID int64, userAgent, ip string) (int64, error) {
+	id, err := s.storage.CreateSession(ctx, userID, userAgent, ip)
+	if err != nil {
+		return 0, fmt.Errorf("storage error of create session:%w", err)
+	}
+
+	return id, nil
+}
+
+// Check возвращает ErrNotFound, если сессия не существует, принадлежит другому пользователю или завершена.
+func (s *session) Check(ctx context.Context, userID, sessionID int64) error {
+	idle, err := s.storage.GetSessionIdle(ctx, userID, sessionID)
+	if err != nil {
+		if errors.Is(err, ports.ErrSessionNotFound) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("storage error of get session idle:%w", err)
+	}
+
+	if idle < touchInterval {
+		return nil
+	}
+
+	err = s.storage.TouchSession(ctx, sessionID)
+	if err != nil {
+		return fmt.Errorf("storage error of touch session:%w", err)
+	}
+
+	return nil
+}
+
+func (s *session) List(ctx context.Context, userID int64) ([]Session, error) {
+	sessions := []Session{}
+
+	dbSessions, err := s.storage.GetSessions(ctx, userID)
+	if err != nil {
+		return sessions, fmt.Errorf("storage error of get sessions:%w", err)
+	}
+
+	for _, dbSession := range dbSessions {
+		sessions = append(sessions, Session(dbSession))
+	}
+
+	return sessions, nil
+}
+
+func (s *session) Terminate(ctx context.Context, userID, sessionID int64) error {
+	err := s.storage.TerminateSession(ctx, userID, sessionID)
+	if err != nil {
+		if errors.Is(err, ports.ErrSessionNotFound) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("storage error of terminate session:%w", err)
+	}
+
+	return nil
+}
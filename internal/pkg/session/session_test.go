@@ -0,0 +1,98 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubSession struct {
+	idle       time.Duration
+	userID     int64
+	terminated bool
+}
+
+// stubStorage хранит сессии в памяти, завершённые и чужие сессии не находятся, как и в БД.
+type stubStorage struct {
+	ports.SessionStorage
+	sessions map[int64]*stubSession
+	touched  []int64
+}
+
+func (s *stubStorage) GetSessionIdle(_ context.Context, userID, sessionID int64) (time.Duration, error) {
+	ss, ok := s.sessions[sessionID]
+	if !ok || ss.userID != userID || ss.terminated {
+		return 0, ports.ErrSessionNotFound
+	}
+	return ss.idle, nil
+}
+
+func (s *stubStorage) TouchSession(_ context.Context, sessionID int64) error {
+	s.touched = append(s.touched, sessionID)
+	return nil
+}
+
+func (s *stubStorage) TerminateSession(_ context.Context, userID, sessionID int64) error {
+	ss, ok := s.sessions[sessionID]
+	if !ok || ss.userID != userID || ss.terminated {
+		return ports.ErrSessionNotFound
+	}
+	ss.terminated = true
+	return nil
+}
+
+func newStubStorage() *stubStorage {
+	return &stubStorage{sessions: map[int64]*stubSession{
+		1: {userID: 1, idle: time.Second},
+		2: {userID: 1, idle: touchInterval},
+		3: {userID: 2, idle: time.Second},
+		4: {userID: 1, idle: time.Second, terminated: true},
+	}}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		sessionID int64
+		touched   []int64
+		err       error
+	}{
+		{name: "Recently used session is not touched", sessionID: 1},
+		{name: "Idle session is touched", sessionID: 2, touched: []int64{2}},
+		{name: "Session of other user", sessionID: 3, err: ErrNotFound},
+		{name: "Terminated session", sessionID: 4, err: ErrNotFound},
+		{name: "Unknown session", sessionID: 5, err: ErrNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newStubStorage()
+
+			err := New(s).Check(context.Background(), 1, test.sessionID)
+			assert.Equal(t, test.touched, s.touched)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTerminate(t *testing.T) {
+	ctx := context.Background()
+	sessions := New(newStubStorage())
+
+	err := sessions.Terminate(ctx, 1, 3)
+	assert.ErrorIs(t, err, ErrNotFound, "session of other user")
+
+	require.NoError(t, sessions.Terminate(ctx, 1, 1))
+	assert.ErrorIs(t, sessions.Check(ctx, 1, 1), ErrNotFound, "terminated session")
+	assert.ErrorIs(t, sessions.Terminate(ctx, 1, 1), ErrNotFound, "already terminated session")
+
+	assert.NoError(t, sessions.Check(ctx, 2, 3), "other sessions stay active")
+}
@@ -0,0 +1,110 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Provider реализует OpenID Connect authorization code flow с внешним провайдером идентификации.
+type Provider interface {
+	AuthCodeURL(state, nonce string) string
+	Exchange(ctx context.Context, code, nonce string) (*Identity, error)
+}
+
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// Identity описывает пользователя провайдера. Пара Issuer и Subject однозначно идентифицирует пользователя,
+// Login используется только как предпочтительный логин при создании пользователя в гофермарте.
+type Identity struct {
+	Issuer  string
+	Subject string
+	Login   string
+}
+
+var (
+	ErrNoIDToken    = errors.New("id_token not found in token response")
+	ErrInvalidNonce = errors.New("invalid nonce")
+)
+
+type provider struct {
+	verifier *oidc.IDTokenVerifier
+	oauth2   oauth2.Config
+	issuer   string
+}
+
+// New получает настройки провайдера через OpenID Connect Discovery.
+func New(ctx context.Context, cfg Config) (Provider, error) {
+	p, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("oidc error of new provider:%w", err)
+	}
+
+	return &provider{
+		issuer:   cfg.Issuer,
+		verifier: p.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     p.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		},
+	}, nil
+}
+
+func (p *provider) AuthCodeURL(state, nonce string) string {
+	return p.oauth2.AuthCodeURL(state, oidc.Nonce(nonce))
+}
+
+func (p *provider) Exchange(ctx context.Context, code, nonce string) (*Identity, error) {
+	token, err := p.oauth2.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2 error of exchange code:%w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, ErrNoIDToken
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc error of verify id_token:%w", err)
+	}
+
+	if idToken.Nonce != nonce {
+		return nil, ErrInvalidNonce
+	}
+
+	var claims struct {
+		PreferredUsername string `json:"preferred_username"`
+		Email             string `json:"email"`
+	}
+	err = idToken.Claims(&claims)
+	if err != nil {
+		return nil, fmt.Errorf("oidc error of parse id_token claims:%w", err)
+	}
+
+	login := claims.PreferredUsername
+	if login == "" {
+		login = claims.Email
+	}
+	if login == "" {
+		login = idToken.Subject
+	}
+
+	return &Identity{
+		Issuer:  p.issuer,
+		Subject: idToken.Subject,
+		Login:   login,
+	}, nil
+}
@@ -0,0 +1,200 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID = "gophermart"
+	testCode     = "code"
+	testNonce    = "nonce"
+)
+
+// stubIdP минимальный OpenID Connect провайдер: discovery, JWKS и token endpoint.
+type stubIdP struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]any
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &stubIdP{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(t, rw, map[string]any{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/auth",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(t, rw, jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{
+			Key:       &key.PublicKey,
+			KeyID:     "test",
+			Algorithm: "RS256",
+			Use:       "sig",
+		}}})
+	})
+	mux.HandleFunc("/token", func(rw http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		if r.PostForm.Get("code") != testCode {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		writeJSON(t, rw, map[string]any{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idp.idToken(t),
+		})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (idp *stubIdP) idToken(t *testing.T) string {
+	t.Helper()
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: idp.key},
+		(&jose.SignerOptions{}).WithType("JWT").WithHeader("kid", "test"))
+	require.NoError(t, err)
+
+	claims := map[string]any{
+		"iss":   idp.server.URL,
+		"aud":   testClientID,
+		"sub":   "subject-1",
+		"nonce": testNonce,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range idp.claims {
+		claims[k] = v
+	}
+
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	require.NoError(t, err)
+
+	return token
+}
+
+func writeJSON(t *testing.T, rw http.ResponseWriter, v any) {
+	t.Helper()
+
+	rw.Header().Set("Content-Type", "application/json")
+	assert.NoError(t, json.NewEncoder(rw).Encode(v))
+}
+
+func TestProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		claims   map[string]any
+		code     string
+		nonce    string
+		identity *Identity
+		wantErr  bool
+	}{
+		{
+			name:   "Login from preferred_username",
+			claims: map[string]any{"preferred_username": "alice", "email": "alice@example.com"},
+			code:   testCode,
+			nonce:  testNonce,
+			identity: &Identity{
+				Subject: "subject-1",
+				Login:   "alice",
+			},
+		},
+		{
+			name:   "Login from email",
+			claims: map[string]any{"email": "alice@example.com"},
+			code:   testCode,
+			nonce:  testNonce,
+			identity: &Identity{
+				Subject: "subject-1",
+				Login:   "alice@example.com",
+			},
+		},
+		{
+			name:  "Login from subject",
+			code:  testCode,
+			nonce: testNonce,
+			identity: &Identity{
+				Subject: "subject-1",
+				Login:   "subject-1",
+			},
+		},
+		{
+			name:    "Invalid nonce",
+			code:    testCode,
+			nonce:   "other",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid code",
+			code:    "other",
+			nonce:   testNonce,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid audience",
+			claims:  map[string]any{"aud": "other"},
+			code:    testCode,
+			nonce:   testNonce,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			idp := newStubIdP(t)
+			idp.claims = test.claims
+
+			p, err := New(context.Background(), Config{
+				Issuer:       idp.server.URL,
+				ClientID:     testClientID,
+				ClientSecret: "secret",
+				RedirectURL:  "http://localhost/api/user/oidc/callback",
+			})
+			require.NoError(t, err)
+
+			u, err := url.Parse(p.AuthCodeURL("state", test.nonce))
+			require.NoError(t, err)
+			assert.Equal(t, "/auth", u.Path)
+			assert.Equal(t, "state", u.Query().Get("state"))
+			assert.Equal(t, test.nonce, u.Query().Get("nonce"))
+
+			identity, err := p.Exchange(context.Background(), test.code, test.nonce)
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			test.identity.Issuer = idp.server.URL
+			assert.Equal(t, test.identity, identity)
+		})
+	}
+}
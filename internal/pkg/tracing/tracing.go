@@ -0,0 +1,69 @@
+// Package tracing настраивает OpenTelemetry: экспорт спанов и распространение контекста трассировки
+// в заголовках запросов.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName имя сервиса в спанах.
+const ServiceName = "gophermart"
+
+// Экспортёры спанов, значения переменной окружения OTEL_TRACES_EXPORTER.
+const (
+	ExporterNone    = "none"
+	ExporterOTLP    = "otlp"
+	ExporterConsole = "console"
+)
+
+// Tracer возвращает трассировщик гофермарта. До вызова Setup спаны никуда не экспортируются.
+func Tracer() trace.Tracer {
+	return otel.Tracer(ServiceName)
+}
+
+// Setup устанавливает глобальный TracerProvider с экспортёром exporter и W3C Trace Context в качестве
+// формата распространения контекста. Адрес коллектора OTLP задаётся стандартными переменными окружения
+// OTEL_EXPORTER_OTLP_ENDPOINT или OTEL_EXPORTER_OTLP_TRACES_ENDPOINT. Возвращает функцию, которая
+// отправляет накопленные спаны и останавливает TracerProvider.
+func Setup(ctx context.Context, exporter string) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	var (
+		exp sdktrace.SpanExporter
+		err error
+	)
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		exp, err = otlptracegrpc.New(ctx)
+	case ExporterConsole:
+		exp, err = stdouttrace.New()
+	default:
+		return nil, fmt.Errorf("unknown traces exporter:%q", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s traces exporter:%w", exporter, err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return tp.Shutdown, nil
+}
@@ -2,16 +2,27 @@ package user
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 
 	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/rs/zerolog/log"
 )
 
 type Managment interface {
 	Create(ctx context.Context, login, password string) (int64, error)
-	GetIDAndPassword(ctx context.Context, login string) (int64, string, error)
+	GetIDAndPassword(ctx context.Context, login string) (int64, string, bool, error)
 	GetBalance(ctx context.Context, userID int64) (float64, float64, error)
+	GetRole(ctx context.Context, userID int64) (string, error)
+	GetIDByIdentity(ctx context.Context, issuer, subject string) (int64, error)
+	CreateWithIdentity(ctx context.Context, login, password, issuer, subject string) (int64, error)
+	CheckActive(ctx context.Context, userID int64) error
+	Delete(ctx context.Context, userID int64) error
+	Lock(ctx context.Context, userID int64) error
+	Unlock(ctx context.Context, userID int64) error
+	ResetPassword(ctx context.Context, userID int64, passwordHash string) error
 }
 
 type user struct {
@@ -21,8 +32,13 @@ type user struct {
 var (
 	ErrLoginAlreadyBusy = errors.New("user login is already busy")
 	ErrNotFound         = errors.New("user not found")
+	ErrIdentityLinked   = errors.New("identity is already linked to user")
+	ErrLocked           = errors.New("user is locked")
+	ErrDeleted          = errors.New("user deleted")
 )
 
+const pseudonymLength = 16
+
 func New(storage ports.UserStorage) Managment {
 	return &user{
 		storage: storage,
@@ -30,7 +46,15 @@ func New(storage ports.UserStorage) Managment {
 }
 
 func (u *user) Create(ctx context.Context, login, password string) (int64, error) {
-	id, err := u.storage.CreateUser(ctx, login, password)
+	tx, err := u.storage.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("storage error of begin transaction:%w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	id, err := u.storage.CreateUser(ctx, tx, login, password)
 	if err != nil {
 		if errors.Is(err, ports.ErrLoginAlreadyBusy) {
 			return 0, ErrLoginAlreadyBusy
@@ -39,20 +63,68 @@ func (u *user) Create(ctx context.Context, login, password string) (int64, error
 		return 0, fmt.Errorf("storage error of create user:%w", err)
 	}
 
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("storage error of commit transaction:%w", err)
+	}
+
 	return id, nil
 }
 
-func (u *user) GetIDAndPassword(ctx context.Context, login string) (int64, string, error) {
-	id, password, err := u.storage.GetUserIDAndPassword(ctx, login)
+// CreateWithIdentity в одной транзакции создаёт пользователя с логином login и привязывает к нему identity.
+// Если login занят, логином становится issuer|subject. Если identity успел привязать параллельный вход,
+// пользователь не создаётся и возвращается ErrIdentityLinked.
+func (u *user) CreateWithIdentity(ctx context.Context, login, password, issuer, subject string) (int64, error) {
+	tx, err := u.storage.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("storage error of begin transaction:%w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	id, err := u.storage.CreateUser(ctx, tx, login, password)
+	if errors.Is(err, ports.ErrLoginAlreadyBusy) {
+		id, err = u.storage.CreateUser(ctx, tx, issuer+"|"+subject, password)
+	}
+	if err != nil {
+		if errors.Is(err, ports.ErrLoginAlreadyBusy) {
+			return 0, ErrLoginAlreadyBusy
+		}
+
+		return 0, fmt.Errorf("storage error of create user:%w", err)
+	}
+
+	err = u.storage.CreateUserIdentity(ctx, tx, id, issuer, subject)
+	if err != nil {
+		if errors.Is(err, ports.ErrIdentityAlreadyAssigned) {
+			return 0, ErrIdentityLinked
+		}
+
+		return 0, fmt.Errorf("storage error of create user identity:%w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("storage error of commit transaction:%w", err)
+	}
+
+	return id, nil
+}
+
+// GetIDAndPassword возвращает идентификатор, хеш пароля и признак блокировки пользователя. Вызывающий
+// сообщает о блокировке (ErrLocked) только после успешной проверки пароля.
+func (u *user) GetIDAndPassword(ctx context.Context, login string) (int64, string, bool, error) {
+	id, password, locked, err := u.storage.GetUserIDAndPassword(ctx, login)
 	if err != nil {
 		if errors.Is(err, ports.ErrUserNotFound) {
-			return id, password, ErrNotFound
+			return id, password, locked, ErrNotFound
 		}
 
-		return id, password, fmt.Errorf("storage error of get user id and password:%w", err)
+		return id, password, locked, fmt.Errorf("storage error of get user id and password:%w", err)
 	}
 
-	return id, password, nil
+	return id, password, locked, nil
 }
 
 func (u *user) GetBalance(ctx context.Context, userID int64) (float64, float64, error) {
@@ -63,3 +135,207 @@ func (u *user) GetBalance(ctx context.Context, userID int64) (float64, float64,
 
 	return current, withdrawn, nil
 }
+
+func (u *user) GetRole(ctx context.Context, userID int64) (string, error) {
+	role, err := u.storage.GetUserRole(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ports.ErrUserNotFound) {
+			return "", ErrNotFound
+		}
+
+		return "", fmt.Errorf("storage error of get user role:%w", err)
+	}
+
+	return role, nil
+}
+
+func (u *user) GetIDByIdentity(ctx context.Context, issuer, subject string) (int64, error) {
+	id, err := u.storage.GetUserIDByIdentity(ctx, issuer, subject)
+	if err != nil {
+		if errors.Is(err, ports.ErrUserNotFound) {
+			return 0, ErrNotFound
+		}
+		if errors.Is(err, ports.ErrUserLocked) {
+			return 0, ErrLocked
+		}
+
+		return 0, fmt.Errorf("storage error of get user id by identity:%w", err)
+	}
+
+	return id, nil
+}
+
+// CheckActive возвращает ErrDeleted, если пользователь удалил аккаунт. Так отзываются все ранее выданные
+// пользователю токены, в том числе токен сессии, созданной входом параллельно с удалением.
+func (u *user) CheckActive(ctx context.Context, userID int64) error {
+	deleted, err := u.storage.IsUserDeleted(ctx, userID)
+	if err != nil {
+		if errors.Is(err, ports.ErrUserNotFound) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("storage error of is user deleted:%w", err)
+	}
+
+	if deleted {
+		return ErrDeleted
+	}
+
+	return nil
+}
+
+// Delete обезличивает пользователя: логин заменяется псевдонимом, пароль стирается, сессии завершаются,
+// API ключи отзываются, привязки к внешним провайдерам и вебхуки удаляются. Заказы и списания остаются
+// и ссылаются на псевдоним.
+func (u *user) Delete(ctx context.Context, userID int64) error {
+	log.Ctx(ctx).Info().Msg("delete user")
+
+	b := make([]byte, pseudonymLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return fmt.Errorf("failed to generate pseudonym:%w", err)
+	}
+	pseudonym := "deleted-" + hex.EncodeToString(b)
+
+	tx, err := u.storage.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("storage error of begin transaction:%w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	err = u.storage.AnonymizeUser(ctx, tx, userID, pseudonym)
+	if err != nil {
+		if errors.Is(err, ports.ErrUserNotFound) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("storage error of anonymize user:%w", err)
+	}
+
+	err = u.storage.TerminateSessions(ctx, tx, userID)
+	if err != nil {
+		return fmt.Errorf("storage error of terminate sessions:%w", err)
+	}
+
+	err = u.storage.RevokeAPIKeys(ctx, tx, userID)
+	if err != nil {
+		return fmt.Errorf("storage error of revoke api keys:%w", err)
+	}
+
+	err = u.storage.DeleteUserIdentities(ctx, tx, userID)
+	if err != nil {
+		return fmt.Errorf("storage error of delete user identities:%w", err)
+	}
+
+	err = u.storage.DeleteWebhooks(ctx, tx, userID)
+	if err != nil {
+		return fmt.Errorf("storage error of delete webhooks:%w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage error of commit transaction:%w", err)
+	}
+
+	return nil
+}
+
+// Lock блокирует пользователя: вход по паролю и через OpenID Connect и запросы по API ключам отклоняются,
+// сессии завершаются. API ключи не отзываются и снова действуют после Unlock.
+func (u *user) Lock(ctx context.Context, userID int64) error {
+	log.Ctx(ctx).Info().Msg("lock user")
+
+	tx, err := u.storage.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("storage error of begin transaction:%w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	err = u.storage.SetUserLocked(ctx, tx, userID, true)
+	if err != nil {
+		if errors.Is(err, ports.ErrUserNotFound) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("storage error of lock user:%w", err)
+	}
+
+	err = u.storage.TerminateSessions(ctx, tx, userID)
+	if err != nil {
+		return fmt.Errorf("storage error of terminate sessions:%w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage error of commit transaction:%w", err)
+	}
+
+	return nil
+}
+
+// Unlock снимает блокировку пользователя.
+func (u *user) Unlock(ctx context.Context, userID int64) error {
+	log.Ctx(ctx).Info().Msg("unlock user")
+
+	tx, err := u.storage.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("storage error of begin transaction:%w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	err = u.storage.SetUserLocked(ctx, tx, userID, false)
+	if err != nil {
+		if errors.Is(err, ports.ErrUserNotFound) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("storage error of unlock user:%w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage error of commit transaction:%w", err)
+	}
+
+	return nil
+}
+
+// ResetPassword заменяет хеш пароля пользователя на passwordHash и завершает все его сессии.
+func (u *user) ResetPassword(ctx context.Context, userID int64, passwordHash string) error {
+	log.Ctx(ctx).Info().Msg("reset password")
+
+	tx, err := u.storage.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("storage error of begin transaction:%w", err)
+	}
+	defer func() {
+		_ = tx.Rollback(ctx)
+	}()
+
+	err = u.storage.UpdatePassword(ctx, tx, userID, passwordHash)
+	if err != nil {
+		if errors.Is(err, ports.ErrUserNotFound) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("storage error of update password:%w", err)
+	}
+
+	err = u.storage.TerminateSessions(ctx, tx, userID)
+	if err != nil {
+		return fmt.Errorf("storage error of terminate sessions:%w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage error of commit transaction:%w", err)
+	}
+
+	return nil
+}
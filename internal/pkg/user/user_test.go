@@ -0,0 +1,191 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubTx struct {
+	pgx.Tx
+	committed bool
+}
+
+func (t *stubTx) Commit(_ context.Context) error {
+	t.committed = true
+	return nil
+}
+
+func (t *stubTx) Rollback(_ context.Context) error {
+	return nil
+}
+
+type stubStorage struct {
+	ports.UserStorage
+	tx           *stubTx
+	busyLogins   map[string]bool
+	linked       bool
+	deleted      map[int64]bool
+	createdLogin string
+	pseudonym    string
+	calls        []string
+}
+
+func (s *stubStorage) AnonymizeUser(_ context.Context, _ pgx.Tx, userID int64, login string) error {
+	if _, ok := s.deleted[userID]; !ok || s.deleted[userID] {
+		return ports.ErrUserNotFound
+	}
+	s.pseudonym = login
+	s.calls = append(s.calls, "AnonymizeUser")
+	return nil
+}
+
+func (s *stubStorage) TerminateSessions(_ context.Context, _ pgx.Tx, _ int64) error {
+	s.calls = append(s.calls, "TerminateSessions")
+	return nil
+}
+
+func (s *stubStorage) RevokeAPIKeys(_ context.Context, _ pgx.Tx, _ int64) error {
+	s.calls = append(s.calls, "RevokeAPIKeys")
+	return nil
+}
+
+func (s *stubStorage) DeleteUserIdentities(_ context.Context, _ pgx.Tx, _ int64) error {
+	s.calls = append(s.calls, "DeleteUserIdentities")
+	return nil
+}
+
+func (s *stubStorage) DeleteWebhooks(_ context.Context, _ pgx.Tx, _ int64) error {
+	s.calls = append(s.calls, "DeleteWebhooks")
+	return nil
+}
+
+func (s *stubStorage) IsUserDeleted(_ context.Context, userID int64) (bool, error) {
+	deleted, ok := s.deleted[userID]
+	if !ok {
+		return false, ports.ErrUserNotFound
+	}
+	return deleted, nil
+}
+
+func (s *stubStorage) BeginTx(_ context.Context) (pgx.Tx, error) {
+	return s.tx, nil
+}
+
+func (s *stubStorage) CreateUser(_ context.Context, _ pgx.Tx, login, _ string) (int64, error) {
+	if s.busyLogins[login] {
+		return 0, ports.ErrLoginAlreadyBusy
+	}
+	s.createdLogin = login
+	return 7, nil
+}
+
+func (s *stubStorage) CreateUserIdentity(_ context.Context, _ pgx.Tx, _ int64, _, _ string) error {
+	if s.linked {
+		return ports.ErrIdentityAlreadyAssigned
+	}
+	return nil
+}
+
+func TestCreateWithIdentity(t *testing.T) {
+	tests := []struct {
+		name       string
+		busyLogins map[string]bool
+		linked     bool
+		login      string
+		err        error
+	}{
+		{name: "Login is free", login: "alice"},
+		{name: "Login is busy", busyLogins: map[string]bool{"alice": true}, login: "https://issuer|sub"},
+		{
+			name:       "Both logins are busy",
+			busyLogins: map[string]bool{"alice": true, "https://issuer|sub": true},
+			err:        ErrLoginAlreadyBusy,
+		},
+		{name: "Identity linked by concurrent login", linked: true, login: "alice", err: ErrIdentityLinked},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &stubStorage{tx: &stubTx{}, busyLogins: test.busyLogins, linked: test.linked}
+			u := New(s)
+
+			id, err := u.CreateWithIdentity(context.Background(), "alice", "hash", "https://issuer", "sub")
+			assert.Equal(t, test.login, s.createdLogin)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				assert.False(t, s.tx.committed, "user must not be created without identity")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, int64(7), id)
+			assert.True(t, s.tx.committed)
+		})
+	}
+}
+
+func TestCheckActive(t *testing.T) {
+	u := New(&stubStorage{deleted: map[int64]bool{1: false, 2: true}})
+
+	tests := []struct {
+		name   string
+		userID int64
+		err    error
+	}{
+		{name: "Active", userID: 1},
+		{name: "Deleted", userID: 2, err: ErrDeleted},
+		{name: "Not found", userID: 3, err: ErrNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := u.CheckActive(context.Background(), test.userID)
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID int64
+		calls  []string
+		err    error
+	}{
+		{
+			name:   "Active user",
+			userID: 1,
+			calls:  []string{"AnonymizeUser", "TerminateSessions", "RevokeAPIKeys", "DeleteUserIdentities", "DeleteWebhooks"},
+		},
+		{name: "Already deleted", userID: 2, err: ErrNotFound},
+		{name: "Not found", userID: 3, err: ErrNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := &stubStorage{tx: &stubTx{}, deleted: map[int64]bool{1: false, 2: true}}
+			u := New(s)
+
+			err := u.Delete(context.Background(), test.userID)
+			assert.Equal(t, test.calls, s.calls)
+			if test.err != nil {
+				require.ErrorIs(t, err, test.err)
+				assert.False(t, s.tx.committed)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, s.tx.committed)
+			assert.Regexp(t, "^deleted-[0-9a-f]{32}$", s.pseudonym)
+		})
+	}
+}
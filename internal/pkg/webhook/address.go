@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress адрес получателя вебхука не публичный: loopback, частная сеть, link-local и т.п.
+// Запросы на такие адреса позволили бы пользователю обращаться от имени сервера во внутреннюю сеть.
+var ErrForbiddenAddress = errors.New("webhook address is not public")
+
+// dialTimeout ограничивает время установки соединения с получателем.
+const dialTimeout = 5 * time.Second
+
+// reservedPrefixes диапазоны, которые netip.Addr не относит к частным, но которые не должны быть получателями
+// вебхуков.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "эта" сеть
+	netip.MustParsePrefix("100.64.0.0/10"),   // CGNAT, RFC 6598
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // документация
+	netip.MustParsePrefix("198.18.0.0/15"),   // тестирование производительности, RFC 2544
+	netip.MustParsePrefix("198.51.100.0/24"), // документация
+	netip.MustParsePrefix("203.0.113.0/24"),  // документация
+	netip.MustParsePrefix("240.0.0.0/4"),     // зарезервировано
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, может транслироваться в частный IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // локальный NAT64, RFC 8215
+	netip.MustParsePrefix("100::/64"),        // discard-only
+	netip.MustParsePrefix("2001::/23"),       // IETF protocol assignments, включая Teredo
+	netip.MustParsePrefix("2001:db8::/32"),   // документация
+	netip.MustParsePrefix("2002::/16"),       // 6to4, может содержать частный IPv4
+	netip.MustParsePrefix("fec0::/10"),       // устаревшие site-local
+}
+
+// isPublic сообщает, что ip - публичный unicast адрес.
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+
+	for _, p := range reservedPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// checkHost проверяет, что все адреса host публичные. host - IP адрес или доменное имя.
+func checkHost(ctx context.Context, host string) error {
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("%w:failed to resolve host %s", ErrInvalidURL, host)
+	}
+
+	for _, ip := range ips {
+		if !isPublic(ip) {
+			return fmt.Errorf("%w:%w:%s", ErrInvalidURL, ErrForbiddenAddress, ip.Unmap())
+		}
+	}
+
+	return nil
+}
+
+// dialControl проверяет адрес непосредственно перед соединением, после разрешения имени. Так смена DNS записи
+// после регистрации вебхука (DNS rebinding) не позволяет обратиться к непубличному адресу.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("failed to parse dial address %s:%w", address, err)
+	}
+
+	if !isPublic(ap.Addr()) {
+		return fmt.Errorf("%w:%s", ErrForbiddenAddress, ap.Addr().Unmap())
+	}
+
+	return nil
+}
+
+// newClient создаёт HTTP клиент доставки вебхуков: соединяется только с публичными адресами, не использует
+// прокси из окружения и не следует перенаправлениям - ответ 3xx считается неудачной попыткой.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: dialTimeout,
+		Control: dialControl,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   requestTimeout,
+		Transport: transport,
+		CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,219 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+const (
+	SignatureHeader = "X-Gophermart-Signature"
+	EventHeader     = "X-Gophermart-Event"
+	DeliveryHeader  = "X-Gophermart-Delivery"
+)
+
+const (
+	// pollInterval период проверки outbox на доставки, время отправки которых наступило.
+	pollInterval = time.Second
+	// batchSize количество доставок, отправляемых параллельно.
+	batchSize = 10
+	// requestTimeout ограничивает время ответа получателя.
+	requestTimeout = 10 * time.Second
+	// lease время, на которое захватывается доставка. Должно быть больше requestTimeout.
+	lease = time.Minute
+	// maxAttempts после стольких неудачных попыток доставка помечается как failed.
+	maxAttempts = 10
+	// Попытки повторяются с экспоненциальной задержкой: baseBackoff, 2*baseBackoff, ..., но не более maxBackoff.
+	baseBackoff = 10 * time.Second
+	maxBackoff  = time.Hour
+	// maxErrorLength ограничивает длину сохраняемого текста ошибки.
+	maxErrorLength = 512
+	// maxResponseLength сколько байт ответа вычитывается, чтобы соединение можно было переиспользовать.
+	maxResponseLength = 64 << 10
+)
+
+// Payload тело запроса вебхука.
+//
+//nolint:govet //incorrectly detects alignment
+type Payload struct {
+	CreatedAt time.Time       `json:"created_at"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	ID        int64           `json:"id"`
+}
+
+type dispatcher struct {
+	storage ports.WebhookStorage
+	client  *http.Client
+}
+
+func NewDispatcher(storage ports.WebhookStorage) *dispatcher {
+	return &dispatcher{
+		storage: storage,
+		client:  newClient(),
+	}
+}
+
+// Run отправляет доставки из outbox до отмены ctx.
+func (d *dispatcher) Run(ctx context.Context) error {
+	log.Ctx(ctx).Info().Msg("run webhook dispatcher")
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Ctx(ctx).Info().AnErr("cause", ctx.Err()).Msg("webhook dispatcher closed")
+			return nil
+		case <-ticker.C:
+			err := d.dispatch(ctx)
+			if err != nil {
+				log.Ctx(ctx).Error().Err(err).Msg("error of dispatch webhooks")
+			}
+		}
+	}
+}
+
+func (d *dispatcher) dispatch(ctx context.Context) error {
+	for {
+		deliveries, err := d.storage.ClaimWebhookDeliveries(ctx, batchSize, lease)
+		if err != nil {
+			return fmt.Errorf("storage error of claim webhook deliveries:%w", err)
+		}
+
+		var wg sync.WaitGroup
+		for i := range deliveries {
+			wg.Add(1)
+			go func(delivery *ports.WebhookDelivery) {
+				defer wg.Done()
+				d.deliver(ctx, delivery)
+			}(&deliveries[i])
+		}
+		wg.Wait()
+
+		if len(deliveries) < batchSize || ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+func (d *dispatcher) deliver(ctx context.Context, delivery *ports.WebhookDelivery) {
+	l := log.Ctx(ctx).With().
+		Int64("webhook_id", delivery.WebhookID).
+		Int64("delivery_id", delivery.ID).
+		Logger()
+	ctx = l.WithContext(ctx)
+
+	attempt := &ports.WebhookDeliveryAttempt{
+		ID:     delivery.ID,
+		Status: StatusDelivered,
+	}
+
+	attempt.StatusCode, attempt.Error = d.send(ctx, delivery)
+	if attempt.Error != "" {
+		log.Ctx(ctx).Warn().
+			Int("attempt", delivery.Attempts+1).
+			Str("error", attempt.Error).
+			Msg("webhook delivery attempt failed")
+
+		attempt.Status = StatusPending
+		attempt.RetryIn = Backoff(delivery.Attempts + 1)
+		if delivery.Attempts+1 >= maxAttempts {
+			attempt.Status = StatusFailed
+		}
+	}
+
+	// Результат сохраняется и при отмене ctx, иначе доставка будет отправлена повторно.
+	err := d.storage.SaveWebhookDeliveryAttempt(context.WithoutCancel(ctx), attempt)
+	if err != nil {
+		log.Ctx(ctx).Error().Err(err).Msg("error of save webhook delivery attempt")
+	}
+}
+
+// send отправляет доставку и возвращает код ответа и текст ошибки. Успешными считаются ответы 2xx.
+func (d *dispatcher) send(ctx context.Context, delivery *ports.WebhookDelivery) (int, string) {
+	body, err := json.Marshal(&Payload{
+		ID:        delivery.ID,
+		Type:      delivery.EventType,
+		CreatedAt: delivery.CreatedAt,
+		Data:      delivery.Payload,
+	})
+	if err != nil {
+		return 0, fmt.Sprintf("error of serialize payload:%s", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, truncate(fmt.Sprintf("error of create request:%s", err))
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, time.Now(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, truncate(err.Error())
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseLength))
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return resp.StatusCode, fmt.Sprintf("unexpected status code:%v", resp.StatusCode)
+	}
+
+	return resp.StatusCode, ""
+}
+
+// Sign возвращает значение заголовка SignatureHeader: t=<unix время>,v1=<hex HMAC-SHA256>.
+// Подписывается строка "<unix время>.<тело запроса>" секретом вебхука. Время в подписи позволяет получателю
+// отклонять повторно отправленные злоумышленником старые запросы.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff возвращает задержку перед следующей попыткой после attempt неудачных попыток.
+func Backoff(attempt int) time.Duration {
+	backoff := baseBackoff
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, maxBackoff)
+}
+
+func truncate(s string) string {
+	if len(s) > maxErrorLength {
+		return s[:maxErrorLength]
+	}
+
+	return s
+}
@@ -0,0 +1,146 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/ports"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 10 * time.Second},
+		{attempt: 2, want: 20 * time.Second},
+		{attempt: 3, want: 40 * time.Second},
+		{attempt: 9, want: 2560 * time.Second},
+		{attempt: 10, want: time.Hour},
+		{attempt: 100, want: time.Hour},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, Backoff(test.attempt), "attempt %v", test.attempt)
+	}
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		wantError  bool
+	}{
+		{
+			name:       "Delivered",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "Server error",
+			statusCode: http.StatusInternalServerError,
+			wantError:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+
+				assert.Equal(t, "order.processed", r.Header.Get(EventHeader))
+				assert.Equal(t, "7", r.Header.Get(DeliveryHeader))
+				assertSignature(t, "secret", r.Header.Get(SignatureHeader), body)
+
+				var p Payload
+				assert.NoError(t, json.Unmarshal(body, &p))
+				assert.Equal(t, int64(7), p.ID)
+				assert.Equal(t, "order.processed", p.Type)
+				assert.JSONEq(t, `{"number":"12345678903","status":"PROCESSED","accrual":500}`, string(p.Data))
+
+				rw.WriteHeader(test.statusCode)
+			}))
+			defer server.Close()
+
+			d := NewDispatcher(nil)
+			// Тестовый сервер слушает loopback, на который клиент доставки не соединяется.
+			d.client.Transport = server.Client().Transport
+			code, errText := d.send(context.Background(), &ports.WebhookDelivery{
+				ID:        7,
+				EventType: "order.processed",
+				Payload:   []byte(`{"number":"12345678903","status":"PROCESSED","accrual":500}`),
+				URL:       server.URL,
+				Secret:    "secret",
+			})
+
+			assert.Equal(t, test.statusCode, code)
+			assert.Equal(t, test.wantError, errText != "")
+		})
+	}
+}
+
+func TestSendRejectsNonPublicAddress(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		requests++
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(nil)
+	code, errText := d.send(context.Background(), &ports.WebhookDelivery{
+		ID:        7,
+		EventType: "order.processed",
+		URL:       server.URL,
+		Secret:    "secret",
+	})
+
+	assert.Equal(t, 0, code)
+	assert.Contains(t, errText, ErrForbiddenAddress.Error())
+	assert.Zero(t, requests)
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		t.Error("redirect is followed")
+	}))
+	defer target.Close()
+
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	d := NewDispatcher(nil)
+	d.client.Transport = server.Client().Transport
+	code, errText := d.send(context.Background(), &ports.WebhookDelivery{
+		ID:        7,
+		EventType: "order.processed",
+		URL:       server.URL,
+		Secret:    "secret",
+	})
+
+	assert.Equal(t, http.StatusTemporaryRedirect, code)
+	assert.NotEmpty(t, errText)
+}
+
+func assertSignature(t *testing.T, secret, header string, body []byte) {
+	t.Helper()
+
+	ts, sig, ok := strings.Cut(header, ",v1=")
+	require.True(t, ok, header)
+	ts = strings.TrimPrefix(ts, "t=")
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "." + string(body)))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), sig)
+}
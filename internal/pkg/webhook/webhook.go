@@ -0,0 +1,203 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/k0st1a/gophermart/internal/pkg/events"
+	"github.com/k0st1a/gophermart/internal/pkg/order"
+	"github.com/k0st1a/gophermart/internal/ports"
+)
+
+type Managment interface {
+	Create(ctx context.Context, userID int64, url string, events []string) (*Webhook, string, error)
+	List(ctx context.Context, userID int64) ([]Webhook, error)
+	Delete(ctx context.Context, userID, webhookID int64) error
+	Deliveries(ctx context.Context, userID, webhookID int64) ([]Delivery, error)
+}
+
+// Events типы событий, на которые можно подписать вебхук: смена статуса заказа на любой статус гофермарта,
+// кроме начального NEW, и списание.
+var Events = []string{
+	events.OrderType(order.StatusProcessing),
+	events.OrderType(order.StatusProcessed),
+	events.OrderType(order.StatusInvalid),
+	events.TypeWithdrawalCreated,
+}
+
+const (
+	secretPrefix = "whsec_"
+	secretLength = 32
+	// deliveriesLimit количество последних доставок в журнале вебхука.
+	deliveriesLimit = 100
+)
+
+//nolint:govet //incorrectly detects alignment
+type Webhook struct {
+	CreatedAt time.Time
+	URL       string
+	Events    []string
+	ID        int64
+}
+
+//nolint:govet //incorrectly detects alignment
+type Delivery struct {
+	CreatedAt      time.Time
+	NextAttemptAt  *time.Time
+	LastAttemptAt  *time.Time
+	DeliveredAt    *time.Time
+	LastStatusCode *int
+	EventType      string
+	Status         string
+	LastError      string
+	Payload        []byte
+	ID             int64
+	Attempts       int
+}
+
+var (
+	ErrInvalidURL   = errors.New("invalid webhook url")
+	ErrEmptyEvents  = errors.New("webhook events are empty")
+	ErrUnknownEvent = errors.New("unknown webhook event")
+	ErrNotFound     = errors.New("webhook not found")
+)
+
+type webhook struct {
+	storage ports.WebhookStorage
+}
+
+func New(storage ports.WebhookStorage) Managment {
+	return &webhook{
+		storage: storage,
+	}
+}
+
+// Create регистрирует вебхук и возвращает секрет подписи. Секрет возвращается только при создании.
+func (w *webhook) Create(ctx context.Context, userID int64, rawURL string, eventTypes []string) (*Webhook, string,
+	error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, "", ErrInvalidURL
+	}
+
+	// Адрес проверяется и при каждой доставке, см. dialControl: DNS запись может измениться после регистрации.
+	err = checkHost(ctx, u.Hostname())
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(eventTypes) == 0 {
+		return nil, "", ErrEmptyEvents
+	}
+
+	for _, e := range eventTypes {
+		if !slices.Contains(Events, e) {
+			return nil, "", fmt.Errorf("%w:%s", ErrUnknownEvent, e)
+		}
+	}
+
+	b := make([]byte, secretLength)
+	_, err = rand.Read(b)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate webhook secret:%w", err)
+	}
+	secret := secretPrefix + hex.EncodeToString(b)
+
+	dbWebhook, err := w.storage.CreateWebhook(ctx, userID, u.String(), secret, eventTypes)
+	if err != nil {
+		return nil, "", fmt.Errorf("storage error of create webhook:%w", err)
+	}
+
+	return toWebhook(dbWebhook), secret, nil
+}
+
+func (w *webhook) List(ctx context.Context, userID int64) ([]Webhook, error) {
+	webhooks := []Webhook{}
+
+	dbWebhooks, err := w.storage.GetWebhooks(ctx, userID)
+	if err != nil {
+		return webhooks, fmt.Errorf("storage error of get webhooks:%w", err)
+	}
+
+	for i := range dbWebhooks {
+		webhooks = append(webhooks, *toWebhook(&dbWebhooks[i]))
+	}
+
+	return webhooks, nil
+}
+
+func (w *webhook) Delete(ctx context.Context, userID, webhookID int64) error {
+	err := w.storage.DeleteWebhook(ctx, userID, webhookID)
+	if err != nil {
+		if errors.Is(err, ports.ErrWebhookNotFound) {
+			return ErrNotFound
+		}
+
+		return fmt.Errorf("storage error of delete webhook:%w", err)
+	}
+
+	return nil
+}
+
+// Deliveries возвращает журнал последних доставок вебхука, начиная с новых.
+func (w *webhook) Deliveries(ctx context.Context, userID, webhookID int64) ([]Delivery, error) {
+	deliveries := []Delivery{}
+
+	dbDeliveries, err := w.storage.GetWebhookDeliveries(ctx, userID, webhookID, deliveriesLimit)
+	if err != nil {
+		if errors.Is(err, ports.ErrWebhookNotFound) {
+			return deliveries, ErrNotFound
+		}
+
+		return deliveries, fmt.Errorf("storage error of get webhook deliveries:%w", err)
+	}
+
+	for i := range dbDeliveries {
+		d := &dbDeliveries[i]
+		delivery := Delivery{
+			ID:        d.ID,
+			EventType: d.EventType,
+			Payload:   d.Payload,
+			Status:    d.Status,
+			Attempts:  d.Attempts,
+			CreatedAt: d.CreatedAt,
+			LastError: d.LastError.String,
+		}
+
+		if d.Status == StatusPending {
+			delivery.NextAttemptAt = &d.NextAttemptAt
+		}
+
+		if d.LastAttemptAt.Valid {
+			delivery.LastAttemptAt = &d.LastAttemptAt.Time
+		}
+
+		if d.DeliveredAt.Valid {
+			delivery.DeliveredAt = &d.DeliveredAt.Time
+		}
+
+		if d.LastStatusCode.Valid {
+			code := int(d.LastStatusCode.Int32)
+			delivery.LastStatusCode = &code
+		}
+
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func toWebhook(w *ports.Webhook) *Webhook {
+	return &Webhook{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    w.Events,
+		CreatedAt: w.CreatedAt,
+	}
+}
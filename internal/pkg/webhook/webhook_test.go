@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/k0st1a/gophermart/internal/pkg/events"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsPublic(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{ip: "127.0.0.1"},
+		{ip: "::1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "169.254.169.254"},
+		{ip: "100.64.0.1"},
+		{ip: "0.0.0.0"},
+		{ip: "255.255.255.255"},
+		{ip: "224.0.0.1"},
+		{ip: "fd00::1"},
+		{ip: "fe80::1"},
+		{ip: "::ffff:10.0.0.1"},
+		{ip: "64:ff9b::a00:1"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, isPublic(netip.MustParseAddr(test.ip)), test.ip)
+	}
+}
+
+func TestCreateRejectsNonPublicURL(t *testing.T) {
+	urls := []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"https://10.0.0.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[fd00::1]/hook",
+		"http://[::ffff:192.168.0.1]/hook",
+	}
+
+	w := New(nil)
+	for _, u := range urls {
+		_, _, err := w.Create(context.Background(), 1, u, []string{"order.processed"})
+		assert.ErrorIs(t, err, ErrInvalidURL, u)
+		assert.ErrorIs(t, err, ErrForbiddenAddress, u)
+	}
+}
+
+// TestOrderEventsSubscribable проверяет, что на событие любого статуса заказа, в который его переводит опрос
+// системы расчёта начислений, можно подписать вебхук.
+func TestOrderEventsSubscribable(t *testing.T) {
+	for _, status := range []string{"PROCESSING", "PROCESSED", "INVALID"} {
+		assert.Contains(t, Events, events.OrderType(status))
+	}
+	assert.NotContains(t, Events, events.OrderType("REGISTERED"))
+}
@@ -4,24 +4,32 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"strconv"
 	"time"
 
+	"github.com/k0st1a/gophermart/internal/pkg/events"
+	"github.com/k0st1a/gophermart/internal/pkg/metrics"
 	"github.com/k0st1a/gophermart/internal/ports"
 	"github.com/rs/zerolog/log"
 )
 
 type Managment interface {
 	Create(ctx context.Context, userID, orderID int64, sum float64) error
-	List(ctx context.Context, userID int64) ([]Withdraw, error)
+	List(ctx context.Context, userID int64, filter ports.ListFilter) ([]Withdraw, error)
 }
 
 type Withdraw struct {
 	ProcessedAt time.Time
+	ID          int64
 	Order       int64
 	Sum         float64
 }
 
-var ErrNotEnoughFunds = errors.New("not enough funds in balance")
+var (
+	ErrNotEnoughFunds = errors.New("not enough funds in balance")
+	ErrInvalidSum     = errors.New("sum must be positive")
+)
 
 type withdraw struct {
 	storage ports.WithdrawStorage
@@ -34,48 +42,73 @@ func New(storage ports.WithdrawStorage) Managment {
 }
 
 func (w *withdraw) Create(ctx context.Context, userID, orderID int64, sum float64) error {
-	log.Printf("Create withdraw, userID:%v, orderID:%v, sum:%v", userID, orderID, sum)
+	log.Ctx(ctx).Debug().Int64("order_id", orderID).Float64("sum", sum).Msg("create withdraw")
+
+	if !ValidSum(sum) {
+		return ErrInvalidSum
+	}
 
 	tx, err := w.storage.BeginTx(ctx)
 	if err != nil {
 		return fmt.Errorf("storage error of begin transaction:%w", err)
 	}
 	defer func() {
-		_ = tx.Commit(ctx)
+		_ = tx.Rollback(ctx)
 	}()
 
 	balance, withdraw, err := w.storage.GetBalanceAndWithdrawnWithBlock(ctx, tx, userID)
 	if err != nil {
-		_ = tx.Rollback(ctx)
 		return fmt.Errorf("storage error of get balance:%w", err)
 	}
-	log.Printf("For userID:%v, balance:%v, withdraw:%v", userID, balance, withdraw)
+	log.Ctx(ctx).Debug().Float64("balance", balance).Float64("withdrawn", withdraw).Msg("got balance")
 
 	if balance < sum {
-		log.Printf("For userID:%v, not enough balance", userID)
-		_ = tx.Rollback(ctx)
+		log.Ctx(ctx).Info().Float64("balance", balance).Float64("sum", sum).Msg("not enough balance")
 		return ErrNotEnoughFunds
 	}
 
 	err = w.storage.UpdateBalanceAndWithdrawn(ctx, tx, userID, balance-sum, withdraw+sum)
 	if err != nil {
-		_ = tx.Rollback(ctx)
 		return fmt.Errorf("storage error of update balance:%w", err)
 	}
 
 	err = w.storage.CreateWithdraw(ctx, tx, userID, orderID, sum)
 	if err != nil {
-		_ = tx.Rollback(ctx)
 		return fmt.Errorf("storage error of create withdraw:%w", err)
 	}
 
+	err = w.storage.CreateUserEvent(ctx, tx, userID, events.TypeBalanceUpdated, events.Balance{Current: balance - sum})
+	if err != nil {
+		return fmt.Errorf("storage error of create balance event:%w", err)
+	}
+
+	err = w.storage.CreateWebhookDeliveries(ctx, tx, userID, events.TypeWithdrawalCreated, events.Withdrawal{
+		Order: strconv.FormatInt(orderID, 10),
+		Sum:   sum,
+	})
+	if err != nil {
+		return fmt.Errorf("storage error of create webhook deliveries:%w", err)
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return fmt.Errorf("storage error of commit transaction:%w", err)
+	}
+
+	metrics.PointsWithdrawn.Add(sum)
+
 	return nil
 }
 
-func (w *withdraw) List(ctx context.Context, userID int64) ([]Withdraw, error) {
-	log.Printf("Get list of withdrawals, userID:%v", userID)
+// ValidSum проверяет, что сумма списания - положительное конечное число.
+func ValidSum(sum float64) bool {
+	return sum > 0 && !math.IsInf(sum, 0)
+}
+
+func (w *withdraw) List(ctx context.Context, userID int64, filter ports.ListFilter) ([]Withdraw, error) {
+	log.Ctx(ctx).Debug().Msg("get list of withdrawals")
 	withdrawals := []Withdraw{}
-	dbWithdrawals, err := w.storage.GetWithdrawals(ctx, userID)
+	dbWithdrawals, err := w.storage.GetWithdrawals(ctx, userID, filter)
 	if err != nil {
 		return withdrawals, fmt.Errorf("storage error of get withdrawals:%w", err)
 	}
@@ -86,6 +119,7 @@ func (w *withdraw) List(ctx context.Context, userID int64) ([]Withdraw, error) {
 		}
 
 		withdrawals = append(withdrawals, Withdraw{
+			ID:          dbWithdraw.ID,
 			Order:       dbWithdraw.Order,
 			Sum:         dbWithdraw.Sum,
 			ProcessedAt: processedAt,
@@ -10,20 +10,42 @@ import (
 )
 
 type UserStorage interface {
-	CreateUser(ctx context.Context, login, password string) (int64, error)
-	GetUserIDAndPassword(ctx context.Context, login string) (int64, string, error)
+	CreateUser(ctx context.Context, tx pgx.Tx, login, password string) (int64, error)
+	GetUserIDAndPassword(ctx context.Context, login string) (int64, string, bool, error)
 	GetBalanceAndWithdrawn(ctx context.Context, userID int64) (float64, float64, error)
+	GetUserRole(ctx context.Context, userID int64) (string, error)
+	GetUserIDByIdentity(ctx context.Context, issuer, subject string) (int64, error)
+	IsUserDeleted(ctx context.Context, userID int64) (bool, error)
+	CreateUserIdentity(ctx context.Context, tx pgx.Tx, userID int64, issuer, subject string) error
+	AnonymizeUser(ctx context.Context, tx pgx.Tx, userID int64, login string) error
+	RevokeAPIKeys(ctx context.Context, tx pgx.Tx, userID int64) error
+	DeleteUserIdentities(ctx context.Context, tx pgx.Tx, userID int64) error
+	TerminateSessions(ctx context.Context, tx pgx.Tx, userID int64) error
+	DeleteWebhooks(ctx context.Context, tx pgx.Tx, userID int64) error
+	SetUserLocked(ctx context.Context, tx pgx.Tx, userID int64, locked bool) error
+	UpdatePassword(ctx context.Context, tx pgx.Tx, userID int64, password string) error
+
+	BeginTx(ctx context.Context) (pgx.Tx, error)
 }
 
 var (
-	ErrLoginAlreadyBusy = errors.New("login is already busy")
-	ErrUserNotFound     = errors.New("user not found")
+	ErrLoginAlreadyBusy        = errors.New("login is already busy")
+	ErrUserNotFound            = errors.New("user not found")
+	ErrUserLocked              = errors.New("user is locked")
+	ErrIdentityAlreadyAssigned = errors.New("identity is already assigned")
 )
 
 type OrderStorage interface {
 	GetUserIDByOrder(ctx context.Context, orderID int64) (int64, error)
 	CreateOrder(ctx context.Context, userID, orderID int64) error
-	GetOrders(ctx context.Context, userID int64) ([]Order, error)
+	GetOrders(ctx context.Context, userID int64, filter ListFilter) ([]Order, error)
+	GetOrder(ctx context.Context, orderID int64) (*Order, error)
+	GetOrderPolls(ctx context.Context, orderID int64) ([]OrderPoll, error)
+	CreateOrders(ctx context.Context, tx pgx.Tx, userID int64, orderIDs []int64) ([]int64, error)
+	GetUserIDsByOrders(ctx context.Context, tx pgx.Tx, orderIDs []int64) (map[int64]int64, error)
+	RequeueOrder(ctx context.Context, orderID int64) error
+
+	BeginTx(ctx context.Context) (pgx.Tx, error)
 }
 
 var (
@@ -38,27 +60,207 @@ type Order struct {
 	Number     int64
 }
 
+// OrderPoll результат одного опроса системы расчёта начислений по заказу.
+type OrderPoll struct {
+	PolledAt time.Time
+	Status   string
+	Accrual  sql.NullFloat64
+}
+
 type WithdrawStorage interface {
 	CreateWithdraw(ctx context.Context, tx pgx.Tx, userID, orderID int64, sum float64) error
 	GetBalanceAndWithdrawnWithBlock(ctx context.Context, tx pgx.Tx, userID int64) (float64, float64, error)
 	UpdateBalanceAndWithdrawn(ctx context.Context, tx pgx.Tx, userID int64, balance, withdrawn float64) error
-	GetWithdrawals(ctx context.Context, userID int64) ([]Withdraw, error)
+	GetWithdrawals(ctx context.Context, userID int64, filter ListFilter) ([]Withdraw, error)
+	CreateUserEvent(ctx context.Context, tx pgx.Tx, userID int64, eventType string, data any) error
+	CreateWebhookDeliveries(ctx context.Context, tx pgx.Tx, userID int64, eventType string, payload any) error
 
 	BeginTx(ctx context.Context) (pgx.Tx, error)
 }
 
 type Withdraw struct {
 	ProcessedAt time.Time
+	ID          int64
 	Order       int64
 	Sum         float64
 }
 
+const (
+	// DefaultListLimit размер страницы списка по умолчанию в API v2, gRPC API и при запросе по курсору.
+	DefaultListLimit = 100
+	// MaxListLimit наибольший размер страницы списка, который может запросить клиент.
+	MaxListLimit = 1000
+)
+
+// ListFilter описывает страницу списка заказов или списаний. After - идентификатор последнего элемента
+// предыдущей страницы (номер заказа или идентификатор списания), 0 - с начала списка.
+// Limit <= 0 означает список без ограничения длины. Statuses применяется только к заказам.
+type ListFilter struct {
+	From     time.Time
+	To       time.Time
+	Statuses []string
+	After    int64
+	Limit    int
+	Desc     bool
+}
+
+// ErrNoOrderToPoll нет заказов, ожидающих опроса системы расчёта начислений, или все они заблокированы
+// параллельными заданиями опроса.
+var ErrNoOrderToPoll = errors.New("no order to poll")
+
 type UpdateOrderStorage interface {
-	GetNotProcessedOrderWithBlock(ctx context.Context, tx pgx.Tx) (int64, error)
+	GetNotProcessedOrderWithBlock(ctx context.Context, tx pgx.Tx) (int64, string, error)
 	GetUserIDByOrderWithBlock(ctx context.Context, tx pgx.Tx, orderID int64) (int64, error)
 	GetBalanceWithBlock(ctx context.Context, tx pgx.Tx, userID int64) (float64, error)
 	UpdateOrder(ctx context.Context, tx pgx.Tx, orderID int64, status string, accrual float64) error
+	CreateOrderPoll(ctx context.Context, tx pgx.Tx, orderID int64, status string, accrual float64) error
 	UpdateBalance(ctx context.Context, tx pgx.Tx, userID int64, balance float64) error
+	CreateUserEvent(ctx context.Context, tx pgx.Tx, userID int64, eventType string, data any) error
+	CreateWebhookDeliveries(ctx context.Context, tx pgx.Tx, userID int64, eventType string, payload any) error
 
 	BeginTx(ctx context.Context) (pgx.Tx, error)
 }
+
+type AdminStorage interface {
+	GetUserByLogin(ctx context.Context, login string) (*User, error)
+	GetBalanceWithBlock(ctx context.Context, tx pgx.Tx, userID int64) (float64, error)
+	UpdateBalance(ctx context.Context, tx pgx.Tx, userID int64, balance float64) error
+	CreateBalanceAdjustment(ctx context.Context, tx pgx.Tx, userID, adminID int64, amount float64, reason string) error
+	CreateUserEvent(ctx context.Context, tx pgx.Tx, userID int64, eventType string, data any) error
+
+	BeginTx(ctx context.Context) (pgx.Tx, error)
+}
+
+//nolint:govet //incorrectly detects alignment
+type User struct {
+	LockedAt  sql.NullTime
+	Login     string
+	Role      string
+	ID        int64
+	Balance   float64
+	Withdrawn float64
+}
+
+type APIKeyStorage interface {
+	CreateAPIKey(ctx context.Context, userID int64, name, hash string, scopes []string) (*APIKey, error)
+	UseAPIKey(ctx context.Context, hash string) (*APIKey, error)
+	GetAPIKeys(ctx context.Context, userID int64) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID int64) error
+}
+
+var (
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+//nolint:govet //incorrectly detects alignment
+type APIKey struct {
+	CreatedAt  time.Time
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+	Name       string
+	Scopes     []string
+	ID         int64
+	UserID     int64
+}
+
+type ExportStorage interface {
+	GetUserByID(ctx context.Context, userID int64) (*User, error)
+	GetBalanceHistory(ctx context.Context, userID int64) ([]BalanceEntry, error)
+}
+
+//nolint:govet //incorrectly detects alignment
+type BalanceEntry struct {
+	At     time.Time
+	Type   string
+	Reason string
+	Order  sql.NullInt64
+	Amount float64
+}
+
+type SessionStorage interface {
+	CreateSession(ctx context.Context, userID int64, userAgent, ip string) (int64, error)
+	GetSessionIdle(ctx context.Context, userID, sessionID int64) (time.Duration, error)
+	TouchSession(ctx context.Context, sessionID int64) error
+	GetSessions(ctx context.Context, userID int64) ([]Session, error)
+	TerminateSession(ctx context.Context, userID, sessionID int64) error
+}
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+type Session struct {
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	UserAgent  string
+	IP         string
+	ID         int64
+}
+
+type EventStorage interface {
+	GetUserEvents(ctx context.Context, userID, afterID int64, limit int) ([]UserEvent, error)
+	GetLastUserEventID(ctx context.Context, userID int64) (int64, error)
+	ListenUserEvents(ctx context.Context, notify func(userID int64)) error
+}
+
+// UserEvent событие пользователя, Data - полезная нагрузка в формате JSON.
+type UserEvent struct {
+	CreatedAt time.Time
+	Type      string
+	Data      []byte
+	ID        int64
+}
+
+type WebhookStorage interface {
+	CreateWebhook(ctx context.Context, userID int64, url, secret string, events []string) (*Webhook, error)
+	GetWebhooks(ctx context.Context, userID int64) ([]Webhook, error)
+	DeleteWebhook(ctx context.Context, userID, webhookID int64) error
+	GetWebhookDeliveries(ctx context.Context, userID, webhookID int64, limit int) ([]WebhookDelivery, error)
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]WebhookDelivery, error)
+	SaveWebhookDeliveryAttempt(ctx context.Context, attempt *WebhookDeliveryAttempt) error
+}
+
+var (
+	ErrWebhookNotFound = errors.New("webhook not found")
+)
+
+//nolint:govet //incorrectly detects alignment
+type Webhook struct {
+	CreatedAt time.Time
+	URL       string
+	Secret    string
+	Events    []string
+	ID        int64
+	UserID    int64
+}
+
+// WebhookDelivery запись исходящего outbox вебхуков. URL и Secret заполняются только при захвате
+// доставки на отправку (ClaimWebhookDeliveries).
+//
+//nolint:govet //incorrectly detects alignment
+type WebhookDelivery struct {
+	CreatedAt      time.Time
+	NextAttemptAt  time.Time
+	LastAttemptAt  sql.NullTime
+	DeliveredAt    sql.NullTime
+	LastError      sql.NullString
+	LastStatusCode sql.NullInt32
+	EventType      string
+	Status         string
+	URL            string
+	Secret         string
+	Payload        []byte
+	ID             int64
+	WebhookID      int64
+	Attempts       int
+}
+
+// WebhookDeliveryAttempt результат попытки доставки. RetryIn - через сколько повторить попытку,
+// если доставка остаётся в статусе pending.
+type WebhookDeliveryAttempt struct {
+	Status     string
+	Error      string
+	ID         int64
+	StatusCode int
+	RetryIn    time.Duration
+}